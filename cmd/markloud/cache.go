@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/markloud/markloud/internal/convert"
+)
+
+// runCache implements "markloud cache prune" and returns the exit code.
+func runCache(args []string) int {
+	if len(args) == 0 || args[0] != "prune" {
+		fmt.Println("usage: markloud cache prune [-dir DIR] [-max-size MB] [-max-age DURATION] [-all]")
+		return 2
+	}
+	fs := flag.NewFlagSet("cache prune", flag.ExitOnError)
+	dir := fs.String("dir", "", "Cache directory (default: user cache dir)")
+	maxMB := fs.Int64("max-size", convert.DefaultCacheMaxBytes>>20, "Shrink the cache to at most this many MB")
+	maxAge := fs.Duration("max-age", 0, "Also remove entries unused for longer than this, e.g. 720h")
+	all := fs.Bool("all", false, "Remove every cached chunk")
+	_ = fs.Parse(args[1:])
+
+	if *dir == "" {
+		d, err := convert.DefaultCacheDir()
+		if err != nil {
+			fmt.Println("error:", err)
+			return 1
+		}
+		*dir = d
+	}
+	limit := *maxMB << 20
+	if *all {
+		limit = 0
+	}
+	cache, err := convert.OpenCache(*dir, limit)
+	if err != nil {
+		fmt.Println("error:", err)
+		return 1
+	}
+	res, err := cache.Prune(limit, *maxAge)
+	if err != nil {
+		fmt.Println("error:", err)
+		return 1
+	}
+	fmt.Printf("removed %d chunks (%s), %s left in %s\n", res.Removed, megabytes(res.RemovedBytes), megabytes(res.Remaining), cache.Dir())
+	return 0
+}
+
+func megabytes(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
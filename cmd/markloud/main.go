@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/markloud/markloud/internal/convert"
 	"github.com/markloud/markloud/internal/ui"
 )
 
@@ -19,10 +22,76 @@ var (
 func main() {
 	_ = godotenv.Load()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cache":
+			os.Exit(runCache(os.Args[2:]))
+		case "prune":
+			os.Exit(runPrune(os.Args[2:]))
+		}
+	}
+
 	inputDir := flag.String("i", "", "Input directory containing markdown files")
 	outputDir := flag.String("o", "", "Output directory for audio files")
-	voice := flag.String("voice", getenv("OPENAI_TTS_VOICE", "alloy"), "TTS voice (alloy, echo, fable, onyx, nova, shimmer)")
+	provider := flag.String("provider", getenv("MARKLOUD_PROVIDER", convert.DefaultProvider), "TTS provider ("+providerNames()+")")
+	baseURL := flag.String("base-url", getenv("MARKLOUD_BASE_URL", ""), "API root for HTTP providers, e.g. http://localhost:8000/v1 (default: OPENAI_BASE_URL or AZURE_OPENAI_ENDPOINT for the OpenAI providers)")
+	apiPath := flag.String("api-path", getenv("MARKLOUD_API_PATH", ""), "Speech endpoint path below the base URL (default /audio/speech)")
+	headers := map[string]string{}
+	headerFlag := func(v string) error {
+		name, value, err := convert.ParseHeader(v)
+		if err != nil {
+			return err
+		}
+		headers[name] = value
+		return nil
+	}
+	for _, h := range strings.Split(os.Getenv("MARKLOUD_HEADERS"), "\n") {
+		if strings.TrimSpace(h) == "" {
+			continue
+		}
+		if err := headerFlag(h); err != nil {
+			fmt.Println("error: MARKLOUD_HEADERS:", err)
+			os.Exit(2)
+		}
+	}
+	flag.Func("header", "Extra HTTP header for API requests, e.g. \"X-Team: docs\" (repeatable)", headerFlag)
+	apiVersion := flag.String("api-version", getenv("OPENAI_API_VERSION", ""), "api-version query parameter (Azure OpenAI)")
+	deployment := flag.String("deployment", getenv("AZURE_OPENAI_DEPLOYMENT", ""), "Azure OpenAI deployment name")
+	retries := flag.Int("retries", convert.DefaultRetryAttempts, "Attempts per TTS request, including the first, for rate limits, server and network errors")
+	retryMaxWait := flag.Duration("retry-max-wait", convert.DefaultRetryMaxWait, "Longest wait between attempts, even when the server asks for more")
+	rpm := flag.Int("rpm", 0, "Requests per minute shared by all workers (0 = unlimited until the provider answers 429)")
+	charsPerMin := flag.Int("chars-per-min", 0, "Characters per minute shared by all workers (0 = unlimited)")
+	command := flag.String("command", getenv("MARKLOUD_COMMAND", ""), "Command line of the command provider (text on stdin, audio on stdout), or the engine path for piper, espeak-ng and festival")
+	commandFormat := flag.String("command-format", "wav", "Audio format written by the command provider")
+	voice := flag.String("voice", "", "TTS voice (default: OPENAI_TTS_VOICE for openai and azure-openai, else the provider's)")
+	format := flag.String("format", "", "Output audio format: mp3, opus, aac, flac, wav or pcm (default: the provider's)")
 	overwrite := flag.Bool("overwrite", false, "Overwrite existing audio files")
+	update := flag.Bool("update", false, "Regenerate existing audio only when its source or settings changed")
+	tables := flag.String("tables", "rows", "How to read tables aloud (rows, cells, skip)")
+	tableRows := flag.Int("table-rows", 0, "Maximum table rows to read aloud (0 = all)")
+	lexicon := flag.String("lexicon", "", "Pronunciation lexicon YAML file")
+	code := flag.String("code", "drop", "How to read code blocks (drop, announce, verbatim, comments)")
+	locale := flag.String("locale", "en", "Language used to expand numbers, dates and symbols")
+	urls := flag.String("urls", "elide", "How to read bare URLs (elide, speak)")
+	workDir := flag.String("work-dir", "", "Directory for per-chunk checkpoints (default <output>/"+convert.WorkDirName+")")
+	cacheDir := flag.String("cache-dir", "", "Audio cache directory (default: user cache dir)")
+	cacheMaxMB := flag.Int64("cache-max-size", convert.DefaultCacheMaxBytes>>20, "Audio cache size limit in MB")
+	noCache := flag.Bool("no-cache", false, "Do not read or write the audio cache")
+	chunkSize := flag.Int("chunk-size", 0, "Maximum characters per TTS request (0 = provider maximum)")
+	sectionLevel := flag.Int("section-level", convert.DefaultSectionLevel, "Deepest heading level where a chunk may break")
+	headingCues := map[int]string{}
+	flag.Func("heading-cue", "Spoken cue for a heading level, e.g. \"2=Section: {title}.\" (repeatable)", func(v string) error {
+		level, cue, err := convert.ParseHeadingCue(v)
+		if err != nil {
+			return err
+		}
+		headingCues[level] = cue
+		return nil
+	})
+	maxChars := flag.Int("max-chars", 0, "Stop the run before sending more than this many characters (0 = no limit)")
+	maxCost := flag.Float64("max-cost", 0, "Stop the run before its estimated cost exceeds this many dollars (0 = no limit)")
+	maxFiles := flag.Int("max-files", 0, "Stop the run before synthesizing more than this many files (0 = no limit)")
+	dryRun := flag.Bool("dry-run", false, "List files, chunks, characters, estimated cost and audio length without synthesizing")
 	showVersion := flag.Bool("version", false, "Print version and exit")
 	flag.Parse()
 
@@ -31,17 +100,95 @@ func main() {
 		return
 	}
 
-	var opts *ui.CLIOptions
-	if *inputDir != "" {
-		if *outputDir == "" {
-			*outputDir = "./audio_out"
-		}
-		opts = &ui.CLIOptions{
-			InputDir:  *inputDir,
-			OutputDir: *outputDir,
-			Voice:     *voice,
-			Overwrite: *overwrite,
-		}
+	info, ok := convert.LookupProvider(*provider)
+	if !ok {
+		fmt.Printf("error: unknown provider %q (available: %s)\n", *provider, providerNames())
+		os.Exit(2)
+	}
+	if *voice == "" && (info.Name == "openai" || info.Name == "azure-openai") {
+		// OPENAI_TTS_VOICE names an OpenAI voice; other providers fall back
+		// to their own default.
+		*voice = getenv("OPENAI_TTS_VOICE", "")
+	}
+	client, err := convert.NewTTSClient(info.Name, convert.ProviderConfig{
+		BaseURL:       *baseURL,
+		Path:          *apiPath,
+		Headers:       headers,
+		APIVersion:    *apiVersion,
+		Deployment:    *deployment,
+		Command:       *command,
+		CommandFormat: *commandFormat,
+		Retry:         convert.RetryPolicy{MaxAttempts: *retries, MaxWait: *retryMaxWait},
+	})
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = info.DefaultFormat
+	}
+	if err := convert.ValidateFormat(client, *format); err != nil {
+		fmt.Println("error:", err)
+		os.Exit(2)
+	}
+	if voices := convert.VoicesOf(client); *voice != "" && len(voices) > 0 && !slices.Contains(voices, *voice) {
+		fmt.Printf("warning: %s does not list voice %q (known: %s); passing it through\n", info.Name, *voice, strings.Join(voices, ", "))
+	}
+	tableMode, err := convert.ParseTableMode(*tables)
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(2)
+	}
+	codeMode, err := convert.ParseCodeBlockMode(*code)
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(2)
+	}
+	urlMode, err := convert.ParseURLMode(*urls)
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(2)
+	}
+
+	if *inputDir != "" && *outputDir == "" {
+		*outputDir = "./audio_out"
+	}
+	opts := &ui.CLIOptions{
+		InputDir:      *inputDir,
+		OutputDir:     *outputDir,
+		Voice:         *voice,
+		Overwrite:     *overwrite,
+		Update:        *update,
+		Format:        strings.ToLower(*format),
+		Provider:      info.Name,
+		BaseURL:       *baseURL,
+		APIPath:       *apiPath,
+		Headers:       headers,
+		APIVersion:    *apiVersion,
+		Deployment:    *deployment,
+		Command:       *command,
+		CommandFormat: *commandFormat,
+		Retries:       *retries,
+		RetryMaxWait:  *retryMaxWait,
+		RPM:           *rpm,
+		CharsPerMin:   *charsPerMin,
+		Tables:        tableMode,
+		TableMaxRows:  *tableRows,
+		CodeBlocks:    codeMode,
+		Lexicon:       *lexicon,
+		Locale:        *locale,
+		URLs:          urlMode,
+		ChunkSize:     *chunkSize,
+		WorkDir:       *workDir,
+		CacheDir:      *cacheDir,
+		CacheMaxBytes: *cacheMaxMB << 20,
+		NoCache:       *noCache,
+		MaxChars:      *maxChars,
+		MaxCost:       *maxCost,
+		MaxFiles:      *maxFiles,
+		DryRun:        *dryRun,
+		SectionLevel:  *sectionLevel,
+		HeadingCues:   headingCues,
 	}
 
 	v := ui.VersionInfo{Version: version, Commit: commit, Date: date}
@@ -51,6 +198,15 @@ func main() {
 	}
 }
 
+// providerNames lists the registered TTS providers for flag help.
+func providerNames() string {
+	var names []string
+	for _, p := range convert.Providers() {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
 func getenv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
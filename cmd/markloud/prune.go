@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/markloud/markloud/internal/convert"
+)
+
+// runPrune implements "markloud prune", which removes or archives audio whose
+// markdown source no longer exists, and returns the exit code.
+func runPrune(args []string) int {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	inputDir := fs.String("i", "", "Input directory containing markdown files")
+	outputDir := fs.String("o", "./audio_out", "Output directory for audio files")
+	archive := fs.String("archive", "", "Move orphaned audio here instead of deleting it")
+	dryRun := fs.Bool("dry-run", false, "Only list orphaned audio")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	_ = fs.Parse(args)
+
+	if *inputDir == "" {
+		fmt.Println("usage: markloud prune -i INPUT [-o OUTPUT] [-archive DIR] [-dry-run] [-yes]")
+		return 2
+	}
+	jobs, err := convert.CollectMarkdownFiles(*inputDir, *outputDir, "*.md", "")
+	if err != nil {
+		fmt.Println("error:", err)
+		return 1
+	}
+	orphans, err := convert.FindOrphans(*outputDir, jobs, *archive)
+	if err != nil {
+		fmt.Println("error:", err)
+		return 1
+	}
+	if len(orphans) == 0 {
+		fmt.Println("no orphaned audio")
+		return 0
+	}
+
+	action := "delete"
+	if *archive != "" {
+		action = "archive to " + *archive
+	}
+	fmt.Printf("%d orphaned files to %s:\n", len(orphans), action)
+	for _, o := range orphans {
+		fmt.Println("  " + o.RelPath)
+	}
+	if *dryRun {
+		return 0
+	}
+	if !*yes {
+		fmt.Print("Proceed? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("aborted")
+			return 1
+		}
+	}
+	if err := convert.RemoveOrphans(*outputDir, orphans, *archive); err != nil {
+		fmt.Println("error:", err)
+		return 1
+	}
+	fmt.Printf("%d files pruned\n", len(orphans))
+	return 0
+}
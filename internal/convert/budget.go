@@ -0,0 +1,75 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrBudgetExceeded is returned (wrapped) when a chunk would take a run past
+// one of its Budget limits.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget caps what a run may send to the provider. Limits of zero are
+// unlimited. A Budget is shared by all workers of a run; a nil Budget allows
+// everything.
+type Budget struct {
+	MaxChars int
+	MaxCost  float64
+	MaxFiles int
+
+	price ModelPrice
+
+	mu    sync.Mutex
+	chars int
+	files map[string]bool
+}
+
+// NewBudget returns a budget for a run using model, which must have a known
+// price when maxCost is set.
+func NewBudget(maxChars int, maxCost float64, maxFiles int, model string) (*Budget, error) {
+	b := &Budget{MaxChars: maxChars, MaxCost: maxCost, MaxFiles: maxFiles, files: map[string]bool{}}
+	if maxCost > 0 {
+		price, ok := PriceFor(model)
+		if !ok {
+			return nil, fmt.Errorf("no known price for model %q, cannot enforce a cost limit", model)
+		}
+		b.price = price
+	}
+	return b, nil
+}
+
+// reserve records that chunk of job is about to be synthesized, or returns
+// ErrBudgetExceeded when doing so would pass a limit.
+func (b *Budget) reserve(job FileJob, chunk string) error {
+	if b == nil {
+		return nil
+	}
+	n := utf8.RuneCountInString(chunk)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.MaxFiles > 0 && !b.files[job.RelPath] && len(b.files) >= b.MaxFiles {
+		return fmt.Errorf("%w: file limit of %d reached", ErrBudgetExceeded, b.MaxFiles)
+	}
+	if b.MaxChars > 0 && b.chars+n > b.MaxChars {
+		return fmt.Errorf("%w: character limit of %d reached", ErrBudgetExceeded, b.MaxChars)
+	}
+	if b.MaxCost > 0 && b.price.Cost(b.chars+n) > b.MaxCost {
+		return fmt.Errorf("%w: cost limit of $%.2f reached", ErrBudgetExceeded, b.MaxCost)
+	}
+	b.chars += n
+	b.files[job.RelPath] = true
+	return nil
+}
+
+// Spent reports the characters and estimated cost reserved so far.
+func (b *Budget) Spent() (chars int, cost float64) {
+	if b == nil {
+		return 0, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.chars, b.price.Cost(b.chars)
+}
@@ -0,0 +1,86 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBudgetLimits(t *testing.T) {
+	a := FileJob{RelPath: "a.md"}
+	b := FileJob{RelPath: "b.md"}
+
+	chars, err := NewBudget(10, 0, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chars.reserve(a, "12345678"); err != nil {
+		t.Fatal(err)
+	}
+	if err := chars.reserve(a, "123"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("reserve past MaxChars = %v, want ErrBudgetExceeded", err)
+	}
+
+	files, _ := NewBudget(0, 0, 1, "")
+	if err := files.reserve(a, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := files.reserve(a, "y"); err != nil {
+		t.Fatalf("more chunks of a started file should be allowed: %v", err)
+	}
+	if err := files.reserve(b, "z"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("reserve past MaxFiles = %v, want ErrBudgetExceeded", err)
+	}
+
+	cost, err := NewBudget(0, 0.01, 0, "tts-1-hd-1106")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cost.reserve(a, string(make([]byte, 300))); err != nil {
+		t.Fatal(err)
+	}
+	if err := cost.reserve(a, string(make([]byte, 100))); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("reserve past MaxCost = %v, want ErrBudgetExceeded", err)
+	}
+	if spent, _ := cost.Spent(); spent != 300 {
+		t.Fatalf("Spent = %d, want 300", spent)
+	}
+
+	if _, err := NewBudget(0, 1, 0, "mystery-model"); err == nil {
+		t.Fatal("cost limit for an unpriced model should fail")
+	}
+}
+
+func TestProcessFileStopsAtBudget(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	if err := os.WriteFile(src, []byte("# One\n\nFirst.\n\n# Two\n\nSecond."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockTTSClient{resp: []byte("A")}
+
+	job := FileJob{AbsPath: src, RelPath: "file.md", DestPath: filepath.Join(root, "out", "file.aac")}
+
+	budget, _ := NewBudget(3, 0, 0, "")
+	res := newTestConverter(t, Config{ResponseFormat: "aac", SectionLevel: 1, ChunkSize: 22, Budget: budget}, mock).ProcessFile(context.Background(), job, nil)
+	if res.Status != JobNotAttempted || !errors.Is(res.Err, ErrBudgetExceeded) || mock.calls != 0 {
+		t.Fatalf("result = %+v after %d calls, want not attempted before any call", res, mock.calls)
+	}
+
+	budget, _ = NewBudget(len("One\n\nFirst.")+1, 0, 0, "")
+	res = newTestConverter(t, Config{ResponseFormat: "aac", SectionLevel: 1, ChunkSize: 22, Budget: budget}, mock).ProcessFile(context.Background(), job, nil)
+	if res.Status != JobFailed || !errors.Is(res.Err, ErrBudgetExceeded) || mock.calls != 1 {
+		t.Fatalf("result = %+v after %d calls, want failed after one call", res, mock.calls)
+	}
+	if _, err := os.Stat(job.DestPath); !os.IsNotExist(err) {
+		t.Fatal("a file stopped by the budget must not be written")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := newTestConverter(t, Config{ResponseFormat: "aac"}, mock).ProcessFile(ctx, job, nil); res.Status != JobNotAttempted {
+		t.Fatalf("cancelled run status = %v, want not attempted", res.Status)
+	}
+}
@@ -0,0 +1,193 @@
+package convert
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheMaxBytes is the cache size limit used when none is configured.
+const DefaultCacheMaxBytes int64 = 2 << 30
+
+// Cache is a content-addressed store of synthesized chunk audio, shared by
+// all runs so that unchanged chunks are never billed twice. Entries are keyed
+// by chunkKey and evicted least-recently-used once the cache outgrows its
+// size limit. A nil Cache stores nothing.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+// CacheStats counts cache lookups.
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// PruneResult reports what Cache.Prune removed.
+type PruneResult struct {
+	Removed      int
+	RemovedBytes int64
+	Remaining    int64
+}
+
+// DefaultCacheDir returns the per-user cache directory for MarkLoud audio.
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "markloud", "audio"), nil
+}
+
+// OpenCache opens (creating if needed) the cache in dir. maxBytes caps its
+// size; 0 means DefaultCacheMaxBytes.
+func OpenCache(dir string, maxBytes int64) (*Cache, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	c := &Cache{dir: dir, maxBytes: maxBytes}
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		c.size += e.size
+	}
+	return c, nil
+}
+
+// Dir returns the cache directory.
+func (c *Cache) Dir() string { return c.dir }
+
+// Get returns the audio stored for key and marks it as recently used.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return data, true
+}
+
+// has reports whether key is cached without marking it as used.
+func (c *Cache) has(key string) bool {
+	if c == nil {
+		return false
+	}
+	info, err := os.Stat(c.path(key))
+	return err == nil && info.Size() > 0
+}
+
+// Put stores audio under key, evicting old entries if the cache is full.
+func (c *Cache) Put(key string, audio []byte) error {
+	if c == nil {
+		return nil
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	// A replaced entry no longer counts towards the cache size.
+	var replaced int64
+	if info, err := os.Stat(path); err == nil {
+		replaced = info.Size()
+	}
+	if err := writeFileAtomic(filepath.Dir(path), filepath.Base(path), audio); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+
+	c.mu.Lock()
+	c.size += int64(len(audio)) - replaced
+	full := c.size > c.maxBytes
+	c.mu.Unlock()
+	if full {
+		// Evict down to 90% so that a full cache is not walked on every Put.
+		if _, err := c.Prune(c.maxBytes*9/10, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune removes the least recently used entries until the cache holds at most
+// maxBytes, and any entry unused for longer than maxAge (when maxAge > 0).
+func (c *Cache) Prune(maxBytes int64, maxAge time.Duration) (PruneResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return PruneResult{}, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+
+	var res PruneResult
+	for _, e := range entries {
+		res.Remaining += e.size
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, e := range entries {
+		if res.Remaining <= maxBytes && (maxAge <= 0 || e.used.After(cutoff)) {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return res, fmt.Errorf("cache: %w", err)
+		}
+		res.Removed++
+		res.RemovedBytes += e.size
+		res.Remaining -= e.size
+	}
+	c.size = res.Remaining
+	return res, nil
+}
+
+// path shards entries by the first two hex digits of their key.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+type cacheEntry struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// entries lists the cached audio files, ignoring unfinished writes.
+func (c *Cache) entries() ([]cacheEntry, error) {
+	var out []cacheEntry
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(d.Name(), partialSuffix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out = append(out, cacheEntry{path: path, size: info.Size(), used: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachePutGet(t *testing.T) {
+	c, err := OpenCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := chunkKey(Config{}, "hello")
+	if _, ok := c.Get(key); ok {
+		t.Fatal("empty cache should miss")
+	}
+	if err := c.Put(key, []byte("AUDIO")); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Get(key)
+	if !ok || string(got) != "AUDIO" {
+		t.Fatalf("Get = %q, %v", got, ok)
+	}
+	if err := c.Put(key, []byte("NEW")); err != nil {
+		t.Fatal(err)
+	}
+	if c.size != 3 {
+		t.Fatalf("size after replacing an entry = %d, want 3", c.size)
+	}
+
+	var nilCache *Cache
+	if _, ok := nilCache.Get(key); ok {
+		t.Fatal("nil cache should miss")
+	}
+	if err := nilCache.Put(key, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachePruneEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := OpenCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{chunkKey(Config{}, "a"), chunkKey(Config{}, "b"), chunkKey(Config{}, "c")}
+	for i, k := range keys {
+		if err := c.Put(k, []byte(strings.Repeat("x", 10))); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(time.Duration(i-10) * time.Hour)
+		if err := os.Chtimes(c.path(k), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := c.Prune(20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 1 || res.Remaining != 20 {
+		t.Fatalf("Prune = %+v, want 1 removed and 20 bytes left", res)
+	}
+	if _, ok := c.Get(keys[0]); ok {
+		t.Fatal("oldest entry should be evicted")
+	}
+
+	res, err = c.Prune(1<<20, 8*time.Hour+30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 1 {
+		t.Fatalf("age prune removed %d, want 1", res.Removed)
+	}
+}
+
+func TestCacheEvictsWhenFull(t *testing.T) {
+	c, err := OpenCache(t.TempDir(), 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"a", "b", "c"} {
+		if err := c.Put(chunkKey(Config{}, text), []byte(strings.Repeat("x", 10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := c.entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	if total > 25 {
+		t.Fatalf("cache holds %d bytes, limit is 25", total)
+	}
+}
+
+func TestProcessFileUsesCache(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	if err := os.WriteFile(src, []byte("# One\n\nFirst.\n\n# Two\n\nSecond."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := OpenCache(filepath.Join(root, "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := FileJob{AbsPath: src, DestPath: filepath.Join(root, "out", "file.aac")}
+	cfg := Config{ResponseFormat: "aac", SectionLevel: 1, ChunkSize: 22, Overwrite: true, Cache: cache}
+
+	mock := &mockTTSClient{resp: []byte("A")}
+	conv := newTestConverter(t, cfg, mock)
+
+	first := conv.ProcessFile(context.Background(), job, nil)
+	if first.Status != JobDone || first.Cache != (CacheStats{Misses: 2}) {
+		t.Fatalf("first run = %+v", first)
+	}
+
+	if err := os.WriteFile(src, []byte("# One\n\nFirst.\n\n# Two\n\nSecond, edited."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	second := conv.ProcessFile(context.Background(), job, nil)
+	if second.Status != JobDone || second.Cache != (CacheStats{Hits: 1, Misses: 1}) {
+		t.Fatalf("second run = %+v", second)
+	}
+	if mock.calls != 3 {
+		t.Fatalf("synthesized %d chunks, want 3", mock.calls)
+	}
+}
@@ -0,0 +1,96 @@
+package convert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WorkDirName is the directory under the output root that holds per-chunk
+// checkpoints when Config.WorkDir is not set.
+const WorkDirName = ".markloud-work"
+
+// checkpoint stores each synthesized chunk of one file so that a failed or
+// interrupted conversion resumes from the first missing chunk. A nil
+// checkpoint stores nothing.
+type checkpoint struct {
+	dir string
+	// ownsRoot is set when the parent directory is the default work dir,
+	// which clear may remove once empty.
+	ownsRoot bool
+}
+
+// openCheckpoint returns the checkpoint store for job, or nil when the run has
+// neither a work directory nor an output root.
+func openCheckpoint(cfg Config, job FileJob) (*checkpoint, error) {
+	root, ownsRoot := cfg.WorkDir, false
+	if root == "" {
+		if cfg.Out == "" {
+			return nil, nil
+		}
+		root, ownsRoot = filepath.Join(cfg.Out, WorkDirName), true
+	}
+	sum := sha256.Sum256([]byte(job.DestPath))
+	dir := filepath.Join(root, hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	return &checkpoint{dir: dir, ownsRoot: ownsRoot}, nil
+}
+
+// chunkKey hashes a chunk's text together with every setting that changes the
+// audio, so that a checkpoint or cache entry is never reused for a different
+// provider, voice or format.
+func chunkKey(cfg Config, text string) string {
+	provider := cfg.Provider
+	if provider == "" {
+		provider = DefaultProvider
+	}
+	h := sha256.New()
+	for _, part := range []string{
+		provider, cfg.Model, cfg.Voice, strconv.FormatFloat(cfg.Speed, 'g', -1, 64),
+		cfg.Instructions, cfg.ResponseFormat, text,
+	} {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load returns the audio saved for key, if any.
+func (c *checkpoint) load(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+// save writes the audio for key, via a temp file so a crash never leaves a
+// truncated chunk behind.
+func (c *checkpoint) save(key string, audio []byte) error {
+	if c == nil {
+		return nil
+	}
+	if err := writeFileAtomic(c.dir, key, audio); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// clear removes the file's checkpoints, and the default work directory once
+// no other file has any left.
+func (c *checkpoint) clear() {
+	if c == nil {
+		return
+	}
+	os.RemoveAll(c.dir)
+	if c.ownsRoot {
+		os.Remove(filepath.Dir(c.dir))
+	}
+}
@@ -0,0 +1,58 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessFileResumesFromCheckpoints(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	if err := os.WriteFile(src, []byte("# One\n\nFirst.\n\n# Two\n\nSecond.\n\n# Three\n\nThird."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(root, "out")
+	job := FileJob{AbsPath: src, DestPath: filepath.Join(out, "file.aac")}
+	cfg := Config{Out: out, ResponseFormat: "aac", SectionLevel: 1, ChunkSize: 22}
+
+	failing := &mockTTSClient{resp: []byte("A"), err: errors.New("outage"), failAt: 3}
+
+	if res := newTestConverter(t, cfg, failing).ProcessFile(context.Background(), job, nil); res.Status != JobFailed {
+		t.Fatalf("first run status = %v, want failed", res.Status)
+	}
+
+	retry := &mockTTSClient{resp: []byte("B")}
+	res := newTestConverter(t, cfg, retry).ProcessFile(context.Background(), job, nil)
+	if res.Status != JobDone {
+		t.Fatalf("second run status = %v (%v), want done", res.Status, res.Err)
+	}
+	if retry.calls != 1 || res.Resumed != 2 {
+		t.Fatalf("second run synthesized %d chunks and resumed %d, want 1 and 2", retry.calls, res.Resumed)
+	}
+	got, err := os.ReadFile(job.DestPath)
+	if err != nil || string(got) != "AAB" {
+		t.Fatalf("dest = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, WorkDirName)); !os.IsNotExist(err) {
+		t.Fatalf("work dir should be removed after success, stat err = %v", err)
+	}
+}
+
+func TestChunkKeyDependsOnSettings(t *testing.T) {
+	base := Config{Model: "m", Voice: "alloy", Speed: 1, ResponseFormat: "aac"}
+	key := chunkKey(base, "hello")
+	if chunkKey(base, "hello") != key {
+		t.Fatal("chunkKey is not stable")
+	}
+	other := base
+	other.Voice = "nova"
+	if chunkKey(other, "hello") == key {
+		t.Fatal("voice change should change the key")
+	}
+	if chunkKey(base, "hello!") == key {
+		t.Fatal("text change should change the key")
+	}
+}
@@ -0,0 +1,346 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultChunkSize is the chunk limit, in runes, used when neither the
+// config nor the TTS client declares one.
+const DefaultChunkSize = 4000
+
+// LimitUnit names what a provider's input limit counts.
+type LimitUnit string
+
+const (
+	// LimitRunes counts Unicode code points ("characters").
+	LimitRunes LimitUnit = "runes"
+	// LimitBytes counts UTF-8 encoded bytes.
+	LimitBytes LimitUnit = "bytes"
+)
+
+// InputLimit is the maximum chunk size a provider accepts per request.
+type InputLimit struct {
+	Max  int
+	Unit LimitUnit
+}
+
+// measure returns the length of s in the limit's unit.
+func (l InputLimit) measure(s string) int {
+	if l.Unit == LimitBytes {
+		return len(s)
+	}
+	return utf8.RuneCountInString(s)
+}
+
+// ChunkLimit returns the chunk limit for a run: the client's declared
+// InputLimit (or DefaultChunkSize runes), lowered to cfg.ChunkSize when set.
+func ChunkLimit(cfg Config, client TTSClient) InputLimit {
+	limit := InputLimit{Max: DefaultChunkSize, Unit: LimitRunes}
+	if l, ok := client.(InputLimiter); ok {
+		if declared := l.InputLimit(); declared.Max > 0 {
+			limit = declared
+			if limit.Unit == "" {
+				limit.Unit = LimitRunes
+			}
+		}
+	}
+	if cfg.ChunkSize > 0 && cfg.ChunkSize < limit.Max {
+		limit.Max = cfg.ChunkSize
+	}
+	return limit
+}
+
+// Chunk is the text of one TTS request and the section heading it belongs to.
+type Chunk struct {
+	Text string
+	// Heading is the section heading in force where the chunk starts, empty
+	// before the first heading.
+	Heading string
+	// Level is the heading level, or 0 when Heading is empty.
+	Level int
+	// Sections lists the headed sections that start inside the chunk.
+	Sections []SectionStart
+}
+
+// SectionStart marks where a section begins inside a chunk.
+type SectionStart struct {
+	Heading string
+	Level   int
+	// Offset is the byte offset in Chunk.Text of the section's first
+	// paragraph, its heading cue when it has one.
+	Offset int
+}
+
+// ChunkSections packs consecutive sections into chunks within limit,
+// preferring to break at headings: a chunk ends before a section that would
+// overflow it, and only a section too long for a chunk of its own is split
+// at paragraph or sentence boundaries.
+func ChunkSections(sections []Section, limit InputLimit) []Chunk {
+	maxLen := limit.Max
+	if maxLen <= 0 {
+		maxLen = DefaultChunkSize
+	}
+	var out []Chunk
+	// add starts a chunk with text, or appends it to the last chunk when
+	// text is a whole section and fits.
+	add := func(sec Section, text string, whole bool, starts []SectionStart) {
+		if n := len(out); n > 0 && whole && limit.measure(out[n-1].Text)+2+limit.measure(text) <= maxLen {
+			cur := &out[n-1]
+			for i := range starts {
+				starts[i].Offset += len(cur.Text) + 2
+			}
+			cur.Text += "\n\n" + text
+			cur.Sections = append(cur.Sections, starts...)
+			return
+		}
+		out = append(out, Chunk{Text: text, Heading: sec.Heading, Level: sec.Level, Sections: starts})
+	}
+	for _, sec := range sections {
+		var pending []SectionStart
+		var lead string
+		if sec.Heading != "" {
+			pending = []SectionStart{{Heading: sec.Heading, Level: sec.Level}}
+			lead = strings.Join(sec.Paragraphs[:sec.lead], "\n\n")
+		}
+		pieces := ChunkTextLimit(sec.Text(), limit)
+		for i, text := range pieces {
+			var starts []SectionStart
+			switch {
+			case pending == nil:
+			case lead == "":
+				starts, pending = pending, nil
+			case strings.HasPrefix(text, lead+"\n\n"):
+				pending[0].Offset = len(lead) + 2
+				starts, pending = pending, nil
+			default:
+				// The piece holds only the lead; the heading opens the next.
+				lead = ""
+			}
+			if i == 0 {
+				add(sec, text, len(pieces) == 1, starts)
+				continue
+			}
+			// The rest of a split section continues it; the last piece may
+			// still take whole sections after it.
+			out = append(out, Chunk{Text: text, Heading: sec.Heading, Level: sec.Level, Sections: starts})
+		}
+	}
+	return out
+}
+
+// ChunkText splits text into chunks of at most maxChars runes at
+// paragraph/sentence boundaries.
+func ChunkText(text string, maxChars int) []string {
+	return ChunkTextLimit(text, InputLimit{Max: maxChars, Unit: LimitRunes})
+}
+
+// ChunkTextLimit splits text into chunks no longer than limit at
+// paragraph/sentence boundaries, measuring in the limit's unit. Sentences that
+// alone exceed the limit are split at whitespace, or between runes as a last
+// resort.
+func ChunkTextLimit(text string, limit InputLimit) []string {
+	maxLen := limit.Max
+	if maxLen <= 0 {
+		maxLen = DefaultChunkSize
+	}
+	paras := strings.Split(text, "\n\n")
+	chunks := make([]string, 0)
+
+	var current []string
+	currentLen := 0
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		chunks = append(chunks, strings.TrimSpace(strings.Join(current, "\n\n")))
+		current = current[:0]
+		currentLen = 0
+	}
+
+	for _, para := range paras {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+
+		paraLen := limit.measure(para)
+		if paraLen > maxLen {
+			flush()
+			var sentences []string
+			for _, s := range SplitSentences(para) {
+				if limit.measure(s) > maxLen {
+					sentences = append(sentences, hardSplit(s, maxLen, limit)...)
+					continue
+				}
+				sentences = append(sentences, s)
+			}
+			buf := make([]string, 0)
+			bufLen := 0
+			for _, s := range sentences {
+				sLen := limit.measure(s)
+				if bufLen+sLen+1 > maxLen {
+					if len(buf) > 0 {
+						chunks = append(chunks, strings.TrimSpace(strings.Join(buf, " ")))
+					}
+					buf = []string{s}
+					bufLen = sLen
+				} else {
+					buf = append(buf, s)
+					bufLen += sLen + 1
+				}
+			}
+			if len(buf) > 0 {
+				chunks = append(chunks, strings.TrimSpace(strings.Join(buf, " ")))
+			}
+			continue
+		}
+
+		if currentLen+paraLen+2 <= maxLen {
+			current = append(current, para)
+			currentLen += paraLen + 2
+		} else {
+			flush()
+			current = append(current, para)
+			currentLen = paraLen
+		}
+	}
+
+	flush()
+	out := make([]string, 0, len(chunks))
+	for _, c := range chunks {
+		if strings.TrimSpace(c) != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// abbreviations are lower-cased words that end with a period without ending
+// the sentence.
+var abbreviations = map[string]bool{
+	"e.g.": true, "i.e.": true, "cf.": true, "vs.": true, "approx.": true,
+	"dr.": true, "mr.": true, "mrs.": true, "ms.": true, "prof.": true,
+	"sr.": true, "jr.": true, "st.": true, "mt.": true, "no.": true,
+	"fig.": true, "eq.": true, "vol.": true, "ch.": true, "sec.": true,
+	"al.": true, "inc.": true, "ltd.": true, "co.": true, "dept.": true,
+	"jan.": true, "feb.": true, "mar.": true, "apr.": true, "jun.": true,
+	"jul.": true, "aug.": true, "sep.": true, "sept.": true, "oct.": true,
+	"nov.": true, "dec.": true,
+}
+
+// versionAbbrevRe matches tokens like "v1." or "v2.3." that end in a period
+// without ending the sentence.
+var versionAbbrevRe = regexp.MustCompile(`^[vV]\d+(\.\d+)*\.$`)
+
+// fullWidthTerminators end CJK sentences, which are not followed by a space.
+const fullWidthTerminators = "。！？"
+
+// SplitSentences splits text into sentences, keeping each sentence's
+// terminating punctuation (and any closing quotes or brackets). A period does
+// not end a sentence after a known abbreviation, a single-letter initial, a
+// version such as "v1.", or when the next word starts in lower case.
+// Full-width terminators such as "。" end a sentence even without a space.
+func SplitSentences(text string) []string {
+	var out []string
+	start := 0
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		fullWidth := strings.ContainsRune(fullWidthTerminators, r)
+		if r != '.' && r != '!' && r != '?' && r != '…' && !fullWidth {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && strings.ContainsRune(".!?…"+fullWidthTerminators, runes[end]) {
+			end++
+		}
+		for end < len(runes) && strings.ContainsRune(`"')]}»”’」』）】》`, runes[end]) {
+			end++
+		}
+		if !fullWidth && end < len(runes) && !unicode.IsSpace(runes[end]) {
+			i = end - 1
+			continue
+		}
+		if r == '.' && end == i+1 && !endsSentence(runes[start:end], runes[end:]) {
+			continue
+		}
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			out = append(out, s)
+		}
+		start = end
+		i = end - 1
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// endsSentence reports whether the period closing sentence is a real
+// sentence boundary given the text that follows it.
+func endsSentence(sentence, rest []rune) bool {
+	fields := strings.Fields(string(sentence))
+	if len(fields) == 0 {
+		return false
+	}
+	word := strings.TrimLeft(fields[len(fields)-1], `"'([{«“‘`)
+	lower := strings.ToLower(word)
+	if abbreviations[lower] || versionAbbrevRe.MatchString(word) {
+		return false
+	}
+	if n := utf8.RuneCountInString(word); n == 2 && unicode.IsUpper([]rune(word)[0]) {
+		return false
+	}
+	for _, r := range rest {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		return !unicode.IsLower(r)
+	}
+	return true
+}
+
+// hardSplit breaks s into pieces no longer than maxLen, preferring whitespace
+// and never cutting inside a rune.
+func hardSplit(s string, maxLen int, limit InputLimit) []string {
+	var out []string
+	var cur strings.Builder
+	for _, word := range strings.Fields(s) {
+		for limit.measure(word) > maxLen {
+			if cur.Len() > 0 {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+			head := word
+			for limit.measure(head) > maxLen {
+				_, size := utf8.DecodeLastRuneInString(head)
+				head = head[:len(head)-size]
+			}
+			if head == "" {
+				_, size := utf8.DecodeRuneInString(word)
+				head = word[:size]
+			}
+			out = append(out, head)
+			word = word[len(head):]
+		}
+		if cur.Len() > 0 && limit.measure(cur.String())+1+limit.measure(word) > maxLen {
+			out = append(out, cur.String())
+			cur.Reset()
+		}
+		if word == "" {
+			continue
+		}
+		if cur.Len() > 0 {
+			cur.WriteByte(' ')
+		}
+		cur.WriteString(word)
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkTextCountsRunes(t *testing.T) {
+	para := strings.Repeat("日本語の文章です。", 20) // 180 runes, 540 bytes
+	text := para + "\n\n" + para
+
+	chunks := ChunkText(text, 400)
+	if len(chunks) != 1 {
+		t.Fatalf("expected multibyte text to fit one 400-rune chunk, got %d", len(chunks))
+	}
+
+	chunks = ChunkTextLimit(text, InputLimit{Max: 600, Unit: LimitBytes})
+	if len(chunks) != 2 {
+		t.Fatalf("expected two byte-limited chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > 600 || !utf8.ValidString(c) {
+			t.Fatalf("chunk %d invalid or over limit: %d bytes", i, len(c))
+		}
+	}
+}
+
+func TestChunkTextKeepsParagraphOrder(t *testing.T) {
+	long := strings.Repeat("Long sentence here. ", 10)
+	text := "Intro.\n\n" + long + "\n\nOutro."
+	chunks := ChunkText(text, 60)
+	if chunks[0] != "Intro." || chunks[len(chunks)-1] != "Outro." {
+		t.Fatalf("paragraph order not preserved: %q", chunks)
+	}
+}
+
+type limitedClient struct {
+	mockTTSClient
+	limit InputLimit
+}
+
+func (c *limitedClient) InputLimit() InputLimit { return c.limit }
+
+func TestChunkLimit(t *testing.T) {
+	var _ InputLimiter = (*openAIClient)(nil)
+
+	if got := ChunkLimit(Config{}, &mockTTSClient{}); got != (InputLimit{Max: DefaultChunkSize, Unit: LimitRunes}) {
+		t.Fatalf("default limit = %+v", got)
+	}
+	client := &limitedClient{limit: InputLimit{Max: 1000, Unit: LimitBytes}}
+	if got := ChunkLimit(Config{}, client); got != client.limit {
+		t.Fatalf("declared limit = %+v", got)
+	}
+	if got := ChunkLimit(Config{ChunkSize: 300}, client); got != (InputLimit{Max: 300, Unit: LimitBytes}) {
+		t.Fatalf("configured limit = %+v", got)
+	}
+	if got := ChunkLimit(Config{ChunkSize: 5000}, client); got.Max != 1000 {
+		t.Fatalf("configured limit above provider max not clamped: %+v", got)
+	}
+	if got := (&openAIClient{}).InputLimit(); got.Max != 4096 {
+		t.Fatalf("openAI limit = %+v", got)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	text := `Dr. Smith wrote v1. of the tool, e.g. the parser. It costs 3.50 a month! Is it "done?" Yes... mostly`
+	want := []string{
+		"Dr. Smith wrote v1. of the tool, e.g. the parser.",
+		"It costs 3.50 a month!",
+		`Is it "done?"`,
+		"Yes...",
+		"mostly",
+	}
+	got := SplitSentences(text)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("SplitSentences() = %q, want %q", got, want)
+	}
+
+	cjk := "日本語のテキストです。これは長い文です！「本当？」はい。"
+	want = []string{"日本語のテキストです。", "これは長い文です！", "「本当？」", "はい。"}
+	if got := SplitSentences(cjk); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("SplitSentences(%q) = %q, want %q", cjk, got, want)
+	}
+	if got := ChunkText("日本語のテキストです。これは長い文です。", 12); len(got) != 2 || got[1] != "これは長い文です。" {
+		t.Fatalf("CJK chunks = %q, want a break after the full stop", got)
+	}
+}
+
+func TestChunkTextKeepsPunctuation(t *testing.T) {
+	text := strings.Repeat("One sentence here. Another one follows! ", 10)
+	for _, c := range ChunkText(text, 100) {
+		if !strings.HasSuffix(c, ".") && !strings.HasSuffix(c, "!") {
+			t.Fatalf("chunk lost its terminating punctuation: %q", c)
+		}
+	}
+}
+
+func TestChunkTextHardSplitsLongSentence(t *testing.T) {
+	text := strings.Repeat("word ", 100) + strings.Repeat("é", 30)
+	chunks := ChunkText(text, 24)
+	if len(chunks) < 2 {
+		t.Fatalf("expected long sentence to be split, got %d chunk(s)", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 24 || !utf8.ValidString(c) {
+			t.Fatalf("chunk %d invalid or over limit: %d runes", i, n)
+		}
+	}
+}
+
+func TestBuildChunksAlignsWithHeadings(t *testing.T) {
+	md := "Intro text.\n\n# Part one\n\nAlpha.\n\n### Detail\n\nBeta.\n\n## Part two\n\nGamma.\n"
+	cfg := Config{HeadingCues: map[int]string{1: "Chapter: {title}.", 2: "… Section: {title}."}}
+
+	// Part two would overflow the first chunk, so the break falls before its
+	// heading rather than inside a section.
+	chunks := BuildChunks(md, "", cfg, InputLimit{Max: 60, Unit: LimitRunes})
+	want := []Chunk{
+		{Text: "Intro text.\n\nChapter: Part one.\n\nAlpha.\n\nDetail\n\nBeta.", Sections: []SectionStart{{Heading: "Part one", Level: 1, Offset: 13}}},
+		{Text: "… Section: Part two.\n\nGamma.", Heading: "Part two", Level: 2, Sections: []SectionStart{{Heading: "Part two", Level: 2}}},
+	}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Fatalf("chunks = %+v, want %+v", chunks, want)
+	}
+}
+
+func TestBuildChunksPacksSections(t *testing.T) {
+	md := "# Note\n\nIntro.\n\n## A\n\nOne.\n\n## B\n\nTwo.\n\n## C\n\nThree.\n\n## D\n\nFour.\n"
+	chunks := BuildChunks(md, "", Config{}, InputLimit{Max: 4000})
+	if len(chunks) != 1 || chunks[0].Heading != "Note" || len(chunks[0].Sections) != 5 {
+		t.Fatalf("want one chunk holding all five sections, got %+v", chunks)
+	}
+	for _, s := range chunks[0].Sections {
+		if !strings.HasPrefix(chunks[0].Text[s.Offset:], s.Heading+"\n\n") {
+			t.Errorf("section %q offset %d points at %q", s.Heading, s.Offset, chunks[0].Text[s.Offset:])
+		}
+	}
+}
+
+func TestBuildChunksSectionLevel(t *testing.T) {
+	md := "# A\n\none\n\n## B\n\ntwo\n"
+	chunks := BuildChunks(md, "", Config{SectionLevel: 1}, InputLimit{Max: 4000})
+	if len(chunks) != 1 || chunks[0].Heading != "A" {
+		t.Fatalf("expected a single chunk under A, got %+v", chunks)
+	}
+}
+
+func TestBuildChunksTitleBeforeFirstHeading(t *testing.T) {
+	chunks := BuildChunks("# Body\n\ntext\n", "Doc title", Config{}, InputLimit{Max: 4000})
+	want := []Chunk{{Text: "Doc title\n\nBody\n\ntext", Heading: "Body", Level: 1, Sections: []SectionStart{{Heading: "Body", Level: 1, Offset: 11}}}}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Fatalf("chunks = %+v, want the title in the first request", chunks)
+	}
+
+	chunks = BuildChunks("# My Title\n\nBody text.\n", "My Title", Config{}, InputLimit{Max: 4000})
+	if len(chunks) != 1 || chunks[0].Text != "My Title\n\nBody text." {
+		t.Fatalf("title matching the first heading should be spoken once, got %+v", chunks)
+	}
+}
+
+func TestParseHeadingCue(t *testing.T) {
+	level, cue, err := ParseHeadingCue("2=Section: {title}.")
+	if err != nil || level != 2 || cue != "Section: {title}." {
+		t.Fatalf("ParseHeadingCue() = %d, %q, %v", level, cue, err)
+	}
+	if _, _, err := ParseHeadingCue("7=x"); err == nil {
+		t.Fatal("expected error for level 7")
+	}
+}
@@ -0,0 +1,159 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// commandClient runs a local TTS engine once per chunk, with the chunk text
+// on stdin and the audio read from stdout.
+type commandClient struct {
+	// argv returns the command line for one chunk.
+	argv   func(cfg Config) ([]string, error)
+	format string
+	// input, when set, rewrites the chunk text written to stdin.
+	input func(chunk string) string
+}
+
+// NewCommandClient returns a client that runs command for every chunk, with
+// the chunk text on stdin and the audio read from stdout. The command line is
+// split like a shell would split it (quotes and backslashes, no expansion),
+// and "{voice}", "{speed}" and "{model}" in any argument are replaced with the
+// run's settings. format is the audio format the command writes; empty means
+// wav.
+func NewCommandClient(command, format string) (TTSClient, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("command provider: no command given")
+	}
+	if format == "" {
+		format = "wav"
+	}
+	format = strings.ToLower(format)
+	if !isAudioFile("x." + format) {
+		return nil, fmt.Errorf("command provider: unsupported format %q", format)
+	}
+	argv := func(cfg Config) ([]string, error) {
+		replacer := strings.NewReplacer(
+			"{voice}", cfg.Voice,
+			"{speed}", formatSpeed(speedOf(cfg)),
+			"{model}", cfg.Model,
+		)
+		out := make([]string, len(args))
+		for i, a := range args {
+			out[i] = replacer.Replace(a)
+		}
+		return out, nil
+	}
+	return &commandClient{argv: argv, format: format}, nil
+}
+
+// Formats reports the single format the command writes.
+func (c *commandClient) Formats() []string {
+	return []string{c.format}
+}
+
+// InputLimit keeps chunks at DefaultChunkSize; local engines have no hard cap,
+// but smaller chunks give finer progress and checkpoints.
+func (c *commandClient) InputLimit() InputLimit {
+	return InputLimit{Max: DefaultChunkSize, Unit: LimitRunes}
+}
+
+func (c *commandClient) Synthesize(ctx context.Context, cfg Config, chunk string) ([]byte, error) {
+	args, err := c.argv(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.input != nil {
+		chunk = c.input(chunk)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(chunk)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is not installed or not on PATH; give its path with -command", args[0])
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 512 {
+			msg = msg[:512] + "…"
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s: wrote no audio", args[0])
+	}
+	return stdout.Bytes(), nil
+}
+
+// speedOf returns the run's speaking speed, 1 when unset.
+func speedOf(cfg Config) float64 {
+	if cfg.Speed <= 0 {
+		return 1
+	}
+	return cfg.Speed
+}
+
+func formatSpeed(f float64) string {
+	return strconv.FormatFloat(f, 'g', 4, 64)
+}
+
+// splitCommand splits a command line into arguments, honouring single and
+// double quotes and backslash escapes.
+func splitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("command: unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("command: trailing backslash")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -1,16 +1,12 @@
 package convert
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 	"time"
 )
@@ -27,6 +23,48 @@ type Config struct {
 	Instructions   string
 	APIKey         string
 	Pattern        string
+	// Update regenerates existing audio only when its manifest shows that the
+	// source or the synthesis settings changed; Overwrite takes precedence.
+	Update bool
+
+	// Tables selects how GFM tables are spoken; empty means TableRows.
+	Tables TableMode
+	// TableMaxRows caps the number of table rows read aloud (0 = no cap).
+	TableMaxRows int
+	// CodeBlocks selects how code blocks are spoken; empty means CodeDrop.
+	CodeBlocks CodeBlockMode
+	// LexiconPath is an optional pronunciation lexicon file given by the user.
+	LexiconPath string
+	// Lexicon rewrites the spoken text before chunking; see ResolveLexicon.
+	Lexicon *Lexicon
+	// Locale selects the language used by Normalize; empty means English.
+	Locale string
+	// URLs selects whether bare URLs are spoken or elided; empty means URLElide.
+	URLs URLMode
+	// ChunkSize caps each chunk in the provider's input units (see ChunkLimit);
+	// 0 uses the provider's declared maximum.
+	ChunkSize int
+	// SectionLevel is the deepest heading level where a chunk may break;
+	// 0 means DefaultSectionLevel.
+	SectionLevel int
+	// HeadingCues maps a heading level to the text spoken for it, with
+	// "{title}" standing for the heading, e.g. "Chapter: {title}.".
+	HeadingCues map[int]string
+	// Provider names the TTS backend, which is part of every cache key; empty
+	// means DefaultProvider.
+	Provider string
+	// Budget caps the characters, cost and files of the run; nil means no
+	// limits.
+	Budget *Budget
+	// RateLimit paces the requests of HTTP providers across all workers;
+	// nil means no pacing.
+	RateLimit *RateLimiter
+	// Cache supplies audio for chunks synthesized by earlier runs; nil
+	// disables caching.
+	Cache *Cache
+	// WorkDir holds per-chunk checkpoints so failed files resume where they
+	// stopped; empty means WorkDirName inside Out.
+	WorkDir string
 }
 
 // FileJob describes one markdown file to convert.
@@ -43,6 +81,9 @@ const (
 	JobSkipped JobOutcome = "skipped"
 	JobEmpty   JobOutcome = "empty"
 	JobFailed  JobOutcome = "failed"
+	// JobNotAttempted means the run was cancelled, or ran out of budget,
+	// before anything was synthesized for the file.
+	JobNotAttempted JobOutcome = "not attempted"
 )
 
 // JobResult represents the result of processing one file.
@@ -50,6 +91,13 @@ type JobResult struct {
 	Status JobOutcome
 	Chunks int
 	Err    error
+	// Parts lists the synthesized chunks in order with their section
+	// headings, for chapter markers and transcripts.
+	Parts []Chunk
+	// Resumed counts chunks taken from checkpoints instead of synthesized.
+	Resumed int
+	// Cache counts chunks found in, or missing from, Config.Cache.
+	Cache CacheStats
 }
 
 // TTSClient abstracts the text-to-speech provider so tests can swap in a mock.
@@ -57,111 +105,36 @@ type TTSClient interface {
 	Synthesize(ctx context.Context, cfg Config, chunk string) ([]byte, error)
 }
 
-type openAIClient struct {
-	httpClient *http.Client
+// FormatLister is implemented by TTS clients that declare which response
+// formats they can produce.
+type FormatLister interface {
+	Formats() []string
 }
 
-var (
-	defaultHTTPClient           = &http.Client{Timeout: 90 * time.Second}
-	ttsClient         TTSClient = &openAIClient{httpClient: defaultHTTPClient}
-)
-
-// SetTTSClient overrides the global TTS client (used in tests).
-func SetTTSClient(c TTSClient) {
-	if c != nil {
-		ttsClient = c
-	}
+// InputLimiter is implemented by TTS clients that declare the largest input
+// they accept in a single request.
+type InputLimiter interface {
+	InputLimit() InputLimit
 }
 
-var (
-	codeFenceRe    = regexp.MustCompile("(?s)```.*?```")
-	inlineCodeRe   = regexp.MustCompile("`([^`]*)`")
-	headingRe      = regexp.MustCompile(`(?m)^#+\s*`)
-	bulletRe       = regexp.MustCompile(`(?m)^[>-]\s*`)
-	linkRe         = regexp.MustCompile(`\[((?:[^\]]|\\])+)]\([^)]+\)`)
-	multiNewlineRe = regexp.MustCompile(`\n{3,}`)
-)
+// DefaultProvider is the TTS backend used when none is configured.
+const DefaultProvider = "openai"
 
-// StripMarkdown removes light Markdown syntax for cleaner TTS output.
-func StripMarkdown(md string) string {
-	md = codeFenceRe.ReplaceAllString(md, "")
-	md = inlineCodeRe.ReplaceAllString(md, "$1")
-	md = headingRe.ReplaceAllString(md, "")
-	md = bulletRe.ReplaceAllString(md, "")
-	md = linkRe.ReplaceAllString(md, "$1")
-	md = multiNewlineRe.ReplaceAllString(md, "\n\n")
-	return strings.TrimSpace(md)
-}
+// DefaultResponseFormat is the audio format used when none is configured.
+const DefaultResponseFormat = "aac"
 
-// ChunkText splits text into roughly maxChars-sized chunks at paragraph/sentence boundaries.
-func ChunkText(text string, maxChars int) []string {
-	if maxChars <= 0 {
-		maxChars = 4000
+// FormatsOf returns the response formats that client produces and that can be
+// joined into a single file. Clients that declare none get every joinable
+// format.
+func FormatsOf(client TTSClient) []string {
+	lister, ok := client.(FormatLister)
+	if !ok {
+		return append([]string(nil), muxFormats...)
 	}
-	paras := strings.Split(text, "\n\n")
-	chunks := make([]string, 0)
-
-	var current []string
-	currentLen := 0
-
-	flush := func() {
-		if len(current) == 0 {
-			return
-		}
-		chunks = append(chunks, strings.TrimSpace(strings.Join(current, "\n\n")))
-		current = current[:0]
-		currentLen = 0
-	}
-
-	for _, para := range paras {
-		para = strings.TrimSpace(para)
-		if para == "" {
-			continue
-		}
-
-		paraLen := len(para)
-		if paraLen > maxChars {
-			sentences := regexp.MustCompile(`[.!?]\s+`).Split(para, -1)
-			buf := make([]string, 0)
-			bufLen := 0
-			for _, s := range sentences {
-				s = strings.TrimSpace(s)
-				if s == "" {
-					continue
-				}
-				sLen := len(s)
-				if bufLen+sLen+1 > maxChars {
-					if len(buf) > 0 {
-						chunks = append(chunks, strings.TrimSpace(strings.Join(buf, " ")))
-					}
-					buf = []string{s}
-					bufLen = sLen
-				} else {
-					buf = append(buf, s)
-					bufLen += sLen + 1
-				}
-			}
-			if len(buf) > 0 {
-				chunks = append(chunks, strings.TrimSpace(strings.Join(buf, " ")))
-			}
-			continue
-		}
-
-		if currentLen+paraLen+2 <= maxChars {
-			current = append(current, para)
-			currentLen += paraLen + 2
-		} else {
-			flush()
-			current = append(current, para)
-			currentLen = paraLen
-		}
-	}
-
-	flush()
-	out := make([]string, 0, len(chunks))
-	for _, c := range chunks {
-		if strings.TrimSpace(c) != "" {
-			out = append(out, c)
+	var out []string
+	for _, f := range lister.Formats() {
+		if slices.Contains(muxFormats, strings.ToLower(f)) {
+			out = append(out, strings.ToLower(f))
 		}
 	}
 	return out
@@ -172,6 +145,9 @@ func CollectMarkdownFiles(root, outDir, pattern, responseFormat string) ([]FileJ
 	if pattern == "" {
 		pattern = "*.md"
 	}
+	if responseFormat == "" {
+		responseFormat = DefaultResponseFormat
+	}
 	root = filepath.Clean(root)
 	outDir = filepath.Clean(outDir)
 
@@ -207,31 +183,118 @@ func CollectMarkdownFiles(root, outDir, pattern, responseFormat string) ([]FileJ
 	return jobs, err
 }
 
-// ProcessFile converts a single file using the configured TTS client.
-func ProcessFile(ctx context.Context, job FileJob, cfg Config, progress func(current, total int)) JobResult {
-	if err := ctx.Err(); err != nil {
-		return JobResult{Status: JobFailed, Err: err}
+// BuildChunks turns a Markdown body into the chunks sent to the TTS client:
+// it renders speakable sections, speaks title first unless the text already
+// opens with it, applies the lexicon and normalization, and packs the
+// sections into chunks within limit.
+func BuildChunks(body, title string, cfg Config, limit InputLimit) []Chunk {
+	sections := SpeakableSections(body, cfg)
+	if title = strings.TrimSpace(title); title != "" && !opensWith(sections, title) {
+		if len(sections) == 0 {
+			sections = []Section{{}}
+		}
+		// The title joins the first section so that it is spoken in the
+		// first request rather than one of its own.
+		sections[0].Paragraphs = append([]string{title}, sections[0].Paragraphs...)
+		sections[0].lead = 1
 	}
+	for i := range sections {
+		for j, p := range sections[i].Paragraphs {
+			sections[i].Paragraphs[j] = Normalize(cfg.Lexicon.Apply(p), cfg)
+		}
+	}
+	return ChunkSections(sections, limit)
+}
 
+// opensWith reports whether sections already start by saying title, as their
+// first heading or first paragraph.
+func opensWith(sections []Section, title string) bool {
+	if len(sections) == 0 {
+		return false
+	}
+	first := sections[0]
+	if strings.EqualFold(strings.TrimSpace(first.Heading), title) {
+		return true
+	}
+	return len(first.Paragraphs) > 0 && strings.HasPrefix(first.Paragraphs[0], title)
+}
+
+// jobPlan is what a file needs once front matter, skip rules and chunking
+// have been applied.
+type jobPlan struct {
+	cfg      Config
+	chunks   []Chunk
+	manifest Manifest
+	// skip is JobSkipped or JobEmpty when nothing needs synthesizing, with
+	// reason saying why.
+	skip   JobOutcome
+	reason string
+}
+
+// planJob reads job's source and decides whether and how it is synthesized.
+func (c *Converter) planJob(job FileJob) (jobPlan, error) {
+	cfg := c.cfg
+	exists := false
 	if !cfg.Overwrite {
 		if _, err := os.Stat(job.DestPath); err == nil {
-			return JobResult{Status: JobSkipped, Chunks: 0, Err: nil}
+			if !cfg.Update {
+				return jobPlan{cfg: cfg, skip: JobSkipped, reason: "audio exists"}, nil
+			}
+			exists = true
 		}
 	}
 
 	data, err := os.ReadFile(job.AbsPath)
 	if err != nil {
-		return JobResult{Status: JobFailed, Err: err}
+		return jobPlan{}, err
+	}
+	fm, body, err := SplitFrontMatter(string(data))
+	if err != nil {
+		return jobPlan{}, err
+	}
+	if fm.Skip {
+		return jobPlan{cfg: cfg, skip: JobSkipped, reason: "skip in front matter"}, nil
 	}
-	plain := StripMarkdown(string(data))
-	if strings.TrimSpace(plain) == "" {
-		return JobResult{Status: JobEmpty}
+	cfg = fm.Apply(cfg)
+
+	manifest := Manifest{
+		Source:       job.RelPath,
+		SourceHash:   hashBytes(data),
+		SettingsHash: settingsHash(cfg),
+	}
+	if exists && upToDate(job.DestPath, manifest.SourceHash, manifest.SettingsHash) {
+		return jobPlan{cfg: cfg, skip: JobSkipped, reason: "up to date"}, nil
 	}
 
-	chunks := ChunkText(plain, 4000)
+	chunks := BuildChunks(body, fm.Title, cfg, ChunkLimit(cfg, c.client))
 	if len(chunks) == 0 {
-		return JobResult{Status: JobEmpty}
+		return jobPlan{cfg: cfg, skip: JobEmpty, reason: "no speakable text"}, nil
+	}
+	return jobPlan{cfg: cfg, chunks: chunks, manifest: manifest}, nil
+}
+
+// ProcessFile converts a single file with the Converter's TTS client. Front
+// matter is stripped from the source and its overrides take precedence over
+// the Converter's Config. progress, when not nil, is called before each chunk.
+func (c *Converter) ProcessFile(ctx context.Context, job FileJob, progress func(current, total int)) JobResult {
+	res := c.processFile(ctx, job, progress)
+	c.logResult(job, res)
+	return res
+}
+
+func (c *Converter) processFile(ctx context.Context, job FileJob, progress func(current, total int)) JobResult {
+	if err := ctx.Err(); err != nil {
+		return JobResult{Status: JobNotAttempted, Err: err}
+	}
+
+	plan, err := c.planJob(job)
+	if err != nil {
+		return JobResult{Status: JobFailed, Err: err}
+	}
+	if plan.skip != "" {
+		return JobResult{Status: plan.skip}
 	}
+	cfg, chunks, manifest := plan.cfg, plan.chunks, plan.manifest
 
 	if err := os.MkdirAll(filepath.Dir(job.DestPath), 0o755); err != nil {
 		return JobResult{Status: JobFailed, Err: err}
@@ -241,112 +304,80 @@ func ProcessFile(ctx context.Context, job FileJob, cfg Config, progress func(cur
 	if progress != nil {
 		progress(0, totalChunks)
 	}
-	var buf bytes.Buffer
+	ckpt, err := openCheckpoint(cfg, job)
+	if err != nil {
+		return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
+	}
+	out, err := createPartial(job.DestPath)
+	if err != nil {
+		return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
+	}
+	defer out.discard()
+	resumed := 0
+	synthesized := false
+	var stats CacheStats
+	mux, err := newAudioMuxer(cfg.ResponseFormat, out.File)
+	if err != nil {
+		return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
+	}
 	for idx, chunk := range chunks {
 		if err := ctx.Err(); err != nil {
-			return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
+			status := JobFailed
+			if !synthesized {
+				status = JobNotAttempted
+			}
+			return JobResult{Status: status, Chunks: totalChunks, Err: err}
 		}
 		if progress != nil {
 			progress(idx+1, totalChunks)
 		}
-		if ttsClient == nil {
-			return JobResult{Status: JobFailed, Chunks: totalChunks, Err: errors.New("tts client not configured")}
-		}
-		chunkAudio, err := ttsClient.Synthesize(ctx, cfg, chunk)
-		if err != nil {
-			return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
+		key := chunkKey(cfg, chunk.Text)
+		chunkAudio, ok := ckpt.load(key)
+		if ok {
+			resumed++
+		} else if chunkAudio, ok = cfg.Cache.Get(key); ok {
+			stats.Hits++
+		} else {
+			if cfg.Cache != nil {
+				stats.Misses++
+			}
+			if c.client == nil {
+				return JobResult{Status: JobFailed, Chunks: totalChunks, Resumed: resumed, Cache: stats, Err: errors.New("tts client not configured")}
+			}
+			if err := cfg.Budget.reserve(job, chunk.Text); err != nil {
+				status := JobFailed
+				if !synthesized {
+					status = JobNotAttempted
+				}
+				return JobResult{Status: status, Chunks: totalChunks, Resumed: resumed, Cache: stats, Err: err}
+			}
+			synthesized = true
+			chunkAudio, err = c.synthesize(ctx, job, cfg, idx, chunk.Text)
+			if err != nil {
+				return JobResult{Status: JobFailed, Chunks: totalChunks, Resumed: resumed, Cache: stats, Err: err}
+			}
+			if err := ckpt.save(key, chunkAudio); err != nil {
+				return JobResult{Status: JobFailed, Chunks: totalChunks, Resumed: resumed, Cache: stats, Err: err}
+			}
+			if err := cfg.Cache.Put(key, chunkAudio); err != nil {
+				return JobResult{Status: JobFailed, Chunks: totalChunks, Resumed: resumed, Cache: stats, Err: err}
+			}
 		}
-		if _, err := buf.Write(chunkAudio); err != nil {
-			return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
+		if err := mux.Add(chunkAudio); err != nil {
+			return JobResult{Status: JobFailed, Chunks: totalChunks, Err: fmt.Errorf("chunk %d: %w", idx+1, err)}
 		}
 	}
-
-	if err := os.WriteFile(job.DestPath, buf.Bytes(), 0o644); err != nil {
+	if err := mux.Finish(); err != nil {
 		return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
 	}
-
-	return JobResult{Status: JobDone, Chunks: len(chunks)}
-}
-
-func (c *openAIClient) Synthesize(ctx context.Context, cfg Config, chunk string) ([]byte, error) {
-	if cfg.APIKey == "" {
-		return nil, errors.New("OPENAI_API_KEY is missing")
-	}
-	if c.httpClient == nil {
-		c.httpClient = defaultHTTPClient
-	}
-
-	payload := map[string]any{
-		"model":           cfg.Model,
-		"input":           chunk,
-		"voice":           cfg.Voice,
-		"response_format": cfg.ResponseFormat,
-	}
-	if cfg.Speed > 0 && cfg.Speed != 1.0 {
-		payload["speed"] = cfg.Speed
-	}
-	if strings.TrimSpace(cfg.Instructions) != "" {
-		payload["instructions"] = cfg.Instructions
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
-	}
-
-	var lastErr error
-	for attempt := 1; attempt <= 3; attempt++ {
-		if attempt > 1 {
-			time.Sleep(time.Duration(attempt*attempt) * 300 * time.Millisecond)
-		}
-
-		var buf bytes.Buffer
-		if err := doTTSRequest(ctx, c.httpClient, cfg.APIKey, body, &buf); err != nil {
-			lastErr = err
-			var apiErr *apiError
-			if errors.As(err, &apiErr) && apiErr.retryable {
-				continue
-			}
-			return nil, err
-		}
-		return buf.Bytes(), nil
-	}
-	if lastErr != nil {
-		return nil, lastErr
-	}
-	return nil, errors.New("unknown TTS error")
-}
-
-type apiError struct {
-	status    string
-	message   string
-	retryable bool
-}
-
-func (e *apiError) Error() string {
-	return fmt.Sprintf("%s: %s", e.status, e.message)
-}
-
-func doTTSRequest(ctx context.Context, client *http.Client, apiKey string, body []byte, w io.Writer) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/audio/speech", bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if err := out.commit(job.DestPath); err != nil {
+		return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		retryable := resp.StatusCode == 429 || resp.StatusCode >= 500
-		return &apiError{status: resp.Status, message: strings.TrimSpace(string(snippet)), retryable: retryable}
+	manifest.Generated = time.Now().UTC()
+	if err := writeManifest(job.DestPath, manifest); err != nil {
+		return JobResult{Status: JobFailed, Chunks: totalChunks, Err: err}
 	}
+	ckpt.clear()
 
-	_, err = io.Copy(w, resp.Body)
-	return err
+	return JobResult{Status: JobDone, Chunks: len(chunks), Parts: chunks, Resumed: resumed, Cache: stats}
 }
@@ -13,17 +13,29 @@ type mockTTSClient struct {
 	chunks []string
 	resp   []byte
 	err    error
+	// failAt makes the call with this 1-based index, and later ones, fail with err.
+	failAt int
 }
 
 func (m *mockTTSClient) Synthesize(_ context.Context, _ Config, chunk string) ([]byte, error) {
 	m.calls++
 	m.chunks = append(m.chunks, chunk)
-	if m.err != nil {
+	if m.err != nil && m.calls >= m.failAt {
 		return nil, m.err
 	}
 	return m.resp, nil
 }
 
+// newTestConverter returns a Converter for cfg that synthesizes with client.
+func newTestConverter(t *testing.T, cfg Config, client TTSClient) *Converter {
+	t.Helper()
+	conv, err := NewConverter(cfg, WithTTSClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conv
+}
+
 func TestStripMarkdown(t *testing.T) {
 	md := "" +
 		"# Title\n\n" +
@@ -95,11 +107,8 @@ func TestProcessFileSkipsExisting(t *testing.T) {
 	}
 
 	mock := &mockTTSClient{}
-	old := ttsClient
-	SetTTSClient(mock)
-	t.Cleanup(func() { SetTTSClient(old) })
 
-	res := ProcessFile(context.Background(), FileJob{AbsPath: src, RelPath: "file.md", DestPath: dest}, Config{Overwrite: false}, nil)
+	res := newTestConverter(t, Config{Overwrite: false}, mock).ProcessFile(context.Background(), FileJob{AbsPath: src, RelPath: "file.md", DestPath: dest}, nil)
 	if res.Status != JobSkipped {
 		t.Fatalf("expected JobSkipped, got %s", res.Status)
 	}
@@ -118,12 +127,9 @@ func TestProcessFileUsesTTSClient(t *testing.T) {
 	}
 
 	mock := &mockTTSClient{resp: []byte("AUDIO")}
-	old := ttsClient
-	SetTTSClient(mock)
-	t.Cleanup(func() { SetTTSClient(old) })
 
 	cfg := Config{Overwrite: true, ResponseFormat: "aac"}
-	res := ProcessFile(context.Background(), FileJob{AbsPath: src, RelPath: "file.md", DestPath: dest}, cfg, nil)
+	res := newTestConverter(t, cfg, mock).ProcessFile(context.Background(), FileJob{AbsPath: src, RelPath: "file.md", DestPath: dest}, nil)
 	if res.Status != JobDone {
 		t.Fatalf("expected JobDone, got %s", res.Status)
 	}
@@ -138,3 +144,41 @@ func TestProcessFileUsesTTSClient(t *testing.T) {
 		t.Fatalf("unexpected audio data %q", string(data))
 	}
 }
+
+func TestCollectMarkdownFilesUsesFormatExtension(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "note.md"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(root, "out")
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "note.aac"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := CollectMarkdownFiles(root, out, "*.md", "mp3")
+	if err != nil {
+		t.Fatalf("CollectMarkdownFiles error: %v", err)
+	}
+	if len(jobs) != 1 || filepath.Base(jobs[0].DestPath) != "note.mp3" {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	client := &openAIClient{}
+	for _, f := range []string{"aac", "mp3", "OPUS", "flac", "wav", "pcm"} {
+		if err := ValidateFormat(client, f); err != nil {
+			t.Errorf("ValidateFormat(%q): %v", f, err)
+		}
+	}
+	if err := ValidateFormat(client, "ogg"); err == nil {
+		t.Error("ValidateFormat(ogg) should fail")
+	}
+
+	if got := FormatsOf(&mockTTSClient{}); len(got) != len(muxFormats) {
+		t.Errorf("clients without FormatLister should get every muxer format, got %v", got)
+	}
+}
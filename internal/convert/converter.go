@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Converter turns Markdown files into audio with its own TTS client, HTTP
+// client, logger and Config. It holds no mutable state of its own, so one
+// Converter may process files from many goroutines, and several Converters
+// with different providers can run side by side in one process.
+type Converter struct {
+	cfg        Config
+	client     TTSClient
+	httpClient *http.Client
+	provider   ProviderConfig
+	logger     *slog.Logger
+}
+
+// Option configures a Converter.
+type Option func(*Converter)
+
+// WithTTSClient makes the Converter synthesize with client instead of
+// building one for Config.Provider.
+func WithTTSClient(client TTSClient) Option {
+	return func(c *Converter) { c.client = client }
+}
+
+// WithProviderConfig configures the client built for Config.Provider.
+func WithProviderConfig(pc ProviderConfig) Option {
+	return func(c *Converter) { c.provider = pc }
+}
+
+// WithHTTPClient sets the HTTP client of the client built for
+// Config.Provider; it has no effect together with WithTTSClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Converter) { c.httpClient = client }
+}
+
+// WithLogger makes the Converter log skipped, converted and failed files, and
+// every synthesized chunk at debug level. The default discards everything.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Converter) { c.logger = logger }
+}
+
+// NewConverter returns a Converter for cfg. Unless WithTTSClient is given, it
+// builds the client of cfg.Provider. A non-empty cfg.ResponseFormat must be
+// one the client produces.
+func NewConverter(cfg Config, opts ...Option) (*Converter, error) {
+	c := &Converter{cfg: cfg}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger == nil {
+		c.logger = slog.New(slog.DiscardHandler)
+	}
+	if c.client == nil {
+		pc := c.provider
+		if c.httpClient != nil {
+			pc.HTTPClient = c.httpClient
+		}
+		client, err := NewTTSClient(cfg.Provider, pc)
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+	}
+	if cfg.ResponseFormat != "" {
+		if err := ValidateFormat(c.client, cfg.ResponseFormat); err != nil {
+			return nil, err
+		}
+	}
+	if voices := VoicesOf(c.client); len(voices) > 0 && !slices.Contains(voices, cfg.Voice) {
+		c.logger.Warn("voice not among the provider's known voices; the API may reject it",
+			"voice", cfg.Voice, "known", strings.Join(voices, ", "))
+	}
+	return c, nil
+}
+
+// Config returns the Converter's configuration.
+func (c *Converter) Config() Config {
+	return c.cfg
+}
+
+// Client returns the TTS client the Converter synthesizes with.
+func (c *Converter) Client() TTSClient {
+	return c.client
+}
+
+// ChunkLimit returns the chunk limit of the Converter's runs; see ChunkLimit.
+func (c *Converter) ChunkLimit() InputLimit {
+	return ChunkLimit(c.cfg, c.client)
+}
+
+// ValidateFormat reports an error when client does not produce format or it
+// cannot be joined into a single file.
+func ValidateFormat(client TTSClient, format string) error {
+	supported := FormatsOf(client)
+	if !slices.Contains(supported, strings.ToLower(format)) {
+		return fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(supported, ", "))
+	}
+	return nil
+}
+
+// synthesize calls the TTS client for one chunk, logging how long it took.
+func (c *Converter) synthesize(ctx context.Context, job FileJob, cfg Config, idx int, text string) ([]byte, error) {
+	start := time.Now()
+	audio, err := c.client.Synthesize(ctx, cfg, text)
+	if err == nil {
+		c.logger.Debug("synthesized chunk", "file", job.RelPath, "chunk", idx+1,
+			"chars", len([]rune(text)), "bytes", len(audio), "elapsed", time.Since(start))
+	}
+	return audio, err
+}
+
+// logResult logs the outcome of one file.
+func (c *Converter) logResult(job FileJob, res JobResult) {
+	switch res.Status {
+	case JobDone:
+		c.logger.Info("converted file", "file", job.RelPath, "dest", job.DestPath, "chunks", res.Chunks,
+			"resumed", res.Resumed, "cache_hits", res.Cache.Hits)
+	case JobFailed:
+		c.logger.Warn("conversion failed", "file", job.RelPath, "err", res.Err)
+	case JobNotAttempted:
+		c.logger.Info("file not attempted", "file", job.RelPath, "err", res.Err)
+	default:
+		c.logger.Debug("skipped file", "file", job.RelPath, "status", string(res.Status))
+	}
+}
@@ -0,0 +1,101 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// echoClient returns its name as the audio of every chunk.
+type echoClient struct{ name string }
+
+func (c echoClient) Synthesize(context.Context, Config, string) ([]byte, error) {
+	return []byte(c.name), nil
+}
+
+func TestConvertersRunSideBySide(t *testing.T) {
+	root := t.TempDir()
+	var jobs []FileJob
+	for i := range 4 {
+		src := filepath.Join(root, fmt.Sprintf("note%d.md", i))
+		if err := os.WriteFile(src, []byte("Hello."), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, FileJob{AbsPath: src, RelPath: filepath.Base(src)})
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b"} {
+		conv := newTestConverter(t, Config{ResponseFormat: "pcm", Overwrite: true}, echoClient{name})
+		for _, job := range jobs {
+			job.DestPath = filepath.Join(root, name, strings.TrimSuffix(job.RelPath, ".md")+".pcm")
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if res := conv.ProcessFile(context.Background(), job, nil); res.Status != JobDone {
+					t.Errorf("%s %s: %+v", name, job.RelPath, res)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	for _, name := range []string{"a", "b"} {
+		got, err := os.ReadFile(filepath.Join(root, name, "note0.pcm"))
+		if err != nil || string(got) != name {
+			t.Errorf("converter %s wrote %q, %v", name, got, err)
+		}
+	}
+}
+
+func TestNewConverter(t *testing.T) {
+	if _, err := NewConverter(Config{Provider: "nope"}); err == nil {
+		t.Error("unknown provider should fail")
+	}
+	if _, err := NewConverter(Config{Provider: "elevenlabs", ResponseFormat: "aac"}); err == nil {
+		t.Error("a format the provider cannot produce should fail")
+	}
+
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_, _ = w.Write([]byte("AUDIO"))
+	}))
+	defer srv.Close()
+	var logs bytes.Buffer
+	conv, err := NewConverter(
+		Config{Provider: "openai-compatible", ResponseFormat: "mp3", Overwrite: true},
+		WithProviderConfig(ProviderConfig{BaseURL: srv.URL}),
+		WithHTTPClient(srv.Client()),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Client().(*openAIClient).httpClient != srv.Client() {
+		t.Error("the provider client should use the Converter's HTTP client")
+	}
+
+	root := t.TempDir()
+	src := filepath.Join(root, "note.md")
+	if err := os.WriteFile(src, []byte("Hello."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job := FileJob{AbsPath: src, RelPath: "note.md", DestPath: filepath.Join(root, "note.mp3")}
+	if res := conv.ProcessFile(context.Background(), job, nil); res.Status != JobDone || hits != 1 {
+		t.Fatalf("result = %+v after %d requests", res, hits)
+	}
+	for _, want := range []string{"synthesized chunk", "converted file", "file=note.md"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log is missing %q:\n%s", want, logs.String())
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// elevenLabsBaseURL is the ElevenLabs API root.
+const elevenLabsBaseURL = "https://api.elevenlabs.io"
+
+// elevenLabsFormats maps response formats to ElevenLabs output_format values.
+var elevenLabsFormats = map[string]string{
+	"mp3":  "mp3_44100_128",
+	"opus": "opus_48000_128",
+	"pcm":  "pcm_24000",
+}
+
+// elevenLabsClient calls the ElevenLabs text-to-speech REST endpoint. Voices
+// are ElevenLabs voice IDs, so any name is passed through.
+type elevenLabsClient struct {
+	httpClient *http.Client
+	retry      RetryPolicy
+	baseURL    string
+	// headers are sent with every request, after the defaults.
+	headers map[string]string
+}
+
+// Formats lists the response formats ElevenLabs can return.
+func (c *elevenLabsClient) Formats() []string {
+	return []string{"mp3", "opus", "pcm"}
+}
+
+// InputLimit reports the 5000-character cap of a single request.
+func (c *elevenLabsClient) InputLimit() InputLimit {
+	return InputLimit{Max: 5000, Unit: LimitRunes}
+}
+
+func (c *elevenLabsClient) Synthesize(ctx context.Context, cfg Config, chunk string) ([]byte, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("ELEVENLABS_API_KEY is missing")
+	}
+	format, ok := elevenLabsFormats[cfg.ResponseFormat]
+	if !ok {
+		return nil, fmt.Errorf("elevenlabs: unsupported format %q", cfg.ResponseFormat)
+	}
+
+	payload := map[string]any{
+		"text":     chunk,
+		"model_id": cfg.Model,
+	}
+	if cfg.Speed > 0 && cfg.Speed != 1.0 {
+		payload["voice_settings"] = map[string]any{"speed": cfg.Speed}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/text-to-speech/%s?output_format=%s",
+		c.baseURL, url.PathEscape(cfg.Voice), url.QueryEscape(format))
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"Accept":       "audio/*",
+		"xi-api-key":   cfg.APIKey,
+	}
+	maps.Copy(headers, c.headers)
+	return postWithRetry(ctx, c.httpClient, c.retry, cfg.RateLimit, utf8.RuneCountInString(chunk), endpoint, headers, body)
+}
@@ -0,0 +1,102 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter holds the per-file settings recognised in a note's YAML (---)
+// or TOML (+++) front matter. Other keys such as author or tags are ignored.
+type FrontMatter struct {
+	Title        string  `yaml:"title" toml:"title"`
+	Voice        string  `yaml:"voice" toml:"voice"`
+	Speed        float64 `yaml:"speed" toml:"speed"`
+	Instructions string  `yaml:"instructions" toml:"instructions"`
+	Skip         bool    `yaml:"skip" toml:"skip"`
+}
+
+// SplitFrontMatter separates a leading front matter block from the Markdown
+// body. Documents without front matter are returned unchanged with a zero
+// FrontMatter. Because "---" is also a thematic break, a leading "---" with no
+// closing fence, or whose block does not start with a "key:" line, is left in
+// the body. A block that does but fails to parse is reported as an error.
+func SplitFrontMatter(src string) (FrontMatter, string, error) {
+	var fm FrontMatter
+	src = strings.TrimPrefix(src, "\ufeff")
+
+	firstLine, rest, ok := cutLine(src)
+	if !ok {
+		return fm, src, nil
+	}
+	fence := strings.TrimSpace(firstLine)
+	if fence != "---" && fence != "+++" {
+		return fm, src, nil
+	}
+
+	var header []string
+	for {
+		line, next, more := cutLine(rest)
+		if strings.TrimSpace(line) == fence || (fence == "---" && strings.TrimSpace(line) == "...") {
+			body := next
+			raw := strings.Join(header, "\n")
+			var err error
+			if fence == "---" {
+				if !looksLikeYAMLHeader(header) {
+					return FrontMatter{}, src, nil
+				}
+				err = yaml.Unmarshal([]byte(raw), &fm)
+			} else {
+				_, err = toml.Decode(raw, &fm)
+			}
+			if err != nil {
+				return FrontMatter{}, src, fmt.Errorf("front matter: %w", err)
+			}
+			return fm, body, nil
+		}
+		if !more {
+			return FrontMatter{}, src, nil
+		}
+		header = append(header, line)
+		rest = next
+	}
+}
+
+// yamlKeyRe matches a line opening a YAML mapping entry, such as "title:".
+var yamlKeyRe = regexp.MustCompile(`^["']?[\w-]+["']?\s*:(\s|$)`)
+
+// looksLikeYAMLHeader reports whether the lines between "---" fences are
+// meant as front matter: empty, or starting with a "key:" line.
+func looksLikeYAMLHeader(lines []string) bool {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return yamlKeyRe.MatchString(line)
+	}
+	return true
+}
+
+// Apply returns cfg with the front matter overrides applied.
+func (fm FrontMatter) Apply(cfg Config) Config {
+	if v := strings.TrimSpace(fm.Voice); v != "" {
+		cfg.Voice = v
+	}
+	if fm.Speed > 0 {
+		cfg.Speed = fm.Speed
+	}
+	if v := strings.TrimSpace(fm.Instructions); v != "" {
+		cfg.Instructions = v
+	}
+	return cfg
+}
+
+// cutLine splits s after its first line, reporting whether a newline was found.
+func cutLine(s string) (line, rest string, found bool) {
+	line, rest, found = strings.Cut(s, "\n")
+	return strings.TrimSuffix(line, "\r"), rest, found
+}
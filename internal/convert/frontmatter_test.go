@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatterYAML(t *testing.T) {
+	src := "---\ntitle: Weekly notes\nauthor: Ada\ntags: [a, b]\nvoice: nova\nspeed: 1.25\n---\n# Body\n"
+	fm, body, err := SplitFrontMatter(src)
+	if err != nil {
+		t.Fatalf("SplitFrontMatter error: %v", err)
+	}
+	if fm.Title != "Weekly notes" || fm.Voice != "nova" || fm.Speed != 1.25 {
+		t.Fatalf("unexpected front matter: %+v", fm)
+	}
+	if body != "# Body\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestSplitFrontMatterTOML(t *testing.T) {
+	src := "+++\ntitle = \"Draft\"\nskip = true\ninstructions = \"Whisper.\"\n+++\nText"
+	fm, body, err := SplitFrontMatter(src)
+	if err != nil {
+		t.Fatalf("SplitFrontMatter error: %v", err)
+	}
+	if !fm.Skip || fm.Title != "Draft" || fm.Instructions != "Whisper." {
+		t.Fatalf("unexpected front matter: %+v", fm)
+	}
+	if body != "Text" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestSplitFrontMatterNone(t *testing.T) {
+	src := "Intro\n\n---\n\nMore"
+	fm, body, err := SplitFrontMatter(src)
+	if err != nil {
+		t.Fatalf("SplitFrontMatter error: %v", err)
+	}
+	if fm != (FrontMatter{}) || body != src {
+		t.Fatalf("expected document unchanged, got %+v %q", fm, body)
+	}
+}
+
+func TestSplitFrontMatterUnclosed(t *testing.T) {
+	// A leading "---" is a thematic break unless it opens a closed mapping.
+	for _, src := range []string{
+		"---\ntitle: x\n",
+		"---\nJust a note with a rule on top.\n",
+		"---\n\nIntro\n\n---\nMore text.\n",
+	} {
+		fm, body, err := SplitFrontMatter(src)
+		if err != nil || body != src || fm != (FrontMatter{}) {
+			t.Errorf("SplitFrontMatter(%q) = %+v, %q, %v; want the whole file as body", src, fm, body, err)
+		}
+	}
+}
+
+func TestSplitFrontMatterMalformed(t *testing.T) {
+	// A closed block starting with a key is front matter even when broken.
+	src := "---\ntitle: [unclosed\nskip: true\n---\nPrivate.\n"
+	if _, _, err := SplitFrontMatter(src); err == nil || !strings.Contains(err.Error(), "front matter:") {
+		t.Fatalf("SplitFrontMatter(%q) error = %v, want a front matter error", src, err)
+	}
+}
+
+type configRecorder struct {
+	mockTTSClient
+	cfgs []Config
+}
+
+func (c *configRecorder) Synthesize(ctx context.Context, cfg Config, chunk string) ([]byte, error) {
+	c.cfgs = append(c.cfgs, cfg)
+	return c.mockTTSClient.Synthesize(ctx, cfg, chunk)
+}
+
+func TestProcessFileAppliesFrontMatter(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	dest := filepath.Join(root, "out", "file.aac")
+	md := "---\ntitle: Release plan\nvoice: onyx\nspeed: 1.5\n---\nShip it.\n"
+	if err := os.WriteFile(src, []byte(md), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &configRecorder{mockTTSClient: mockTTSClient{resp: []byte("AUDIO")}}
+
+	cfg := Config{Overwrite: true, ResponseFormat: "aac", Voice: "alloy", Speed: 1.0}
+	res := newTestConverter(t, cfg, rec).ProcessFile(context.Background(), FileJob{AbsPath: src, RelPath: "file.md", DestPath: dest}, nil)
+	if res.Status != JobDone {
+		t.Fatalf("expected JobDone, got %s (%v)", res.Status, res.Err)
+	}
+	if len(rec.cfgs) != 1 || rec.cfgs[0].Voice != "onyx" || rec.cfgs[0].Speed != 1.5 {
+		t.Fatalf("front matter overrides not applied: %+v", rec.cfgs)
+	}
+	if want := "Release plan\n\nShip it."; rec.chunks[0] != want {
+		t.Fatalf("chunk = %q, want %q", rec.chunks[0], want)
+	}
+}
+
+func TestProcessFileFrontMatterSkip(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	if err := os.WriteFile(src, []byte("---\nskip: true\n---\nPrivate.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &mockTTSClient{}
+
+	dest := filepath.Join(root, "out", "file.aac")
+	res := newTestConverter(t, Config{Overwrite: true}, mock).ProcessFile(context.Background(), FileJob{AbsPath: src, RelPath: "file.md", DestPath: dest}, nil)
+	if res.Status != JobSkipped {
+		t.Fatalf("expected JobSkipped, got %s", res.Status)
+	}
+	if mock.calls != 0 {
+		t.Fatalf("expected TTS not to be called, got %d", mock.calls)
+	}
+}
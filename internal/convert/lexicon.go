@@ -0,0 +1,171 @@
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LexiconEntry maps a word, phrase or pattern to the form the TTS should say.
+type LexiconEntry struct {
+	Match string `yaml:"match"`
+	Say   string `yaml:"say"`
+	// Regex treats Match as a regular expression; Say may use $1-style groups.
+	Regex bool `yaml:"regex"`
+	// CaseSensitive disables the default case-insensitive matching.
+	CaseSensitive bool `yaml:"case_sensitive"`
+	// Partial allows literal matches inside longer words.
+	Partial bool `yaml:"partial"`
+}
+
+// Lexicon is a compiled, ordered list of pronunciation rules. Earlier entries
+// win: once text is replaced, later rules only see the spoken form.
+type Lexicon struct {
+	rules []lexiconRule
+}
+
+type lexiconRule struct {
+	re      *regexp.Regexp
+	say     string
+	literal bool
+}
+
+// lexiconFile is the on-disk layout of a lexicon YAML file.
+type lexiconFile struct {
+	Entries []LexiconEntry `yaml:"entries"`
+}
+
+// NewLexicon compiles entries into a Lexicon.
+func NewLexicon(entries []LexiconEntry) (*Lexicon, error) {
+	l := &Lexicon{}
+	for i, e := range entries {
+		if e.Match == "" {
+			return nil, fmt.Errorf("lexicon entry %d: match is empty", i+1)
+		}
+		pattern := e.Match
+		if !e.Regex {
+			pattern = regexp.QuoteMeta(e.Match)
+			if !e.Partial {
+				pattern = wordBoundary(e.Match, true) + pattern + wordBoundary(e.Match, false)
+			}
+		}
+		if !e.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("lexicon entry %d (%q): %w", i+1, e.Match, err)
+		}
+		l.rules = append(l.rules, lexiconRule{re: re, say: e.Say, literal: !e.Regex})
+	}
+	return l, nil
+}
+
+// LoadLexicon reads a YAML lexicon file with a top-level "entries" list.
+func LoadLexicon(path string) (*Lexicon, error) {
+	entries, err := readLexiconEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewLexicon(entries)
+}
+
+// ResolveLexicon builds the lexicon for a run from an optional file given on
+// the command line and the project config in root. Command-line entries take
+// precedence over project entries. It returns nil when neither defines any.
+func ResolveLexicon(root, path string) (*Lexicon, error) {
+	var entries []LexiconEntry
+	if path != "" {
+		fileEntries, err := readLexiconEntries(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	project, err := LoadProjectConfig(root)
+	if err != nil {
+		return nil, err
+	}
+	if project.Lexicon != "" {
+		projectPath := project.Lexicon
+		if !filepath.IsAbs(projectPath) {
+			projectPath = filepath.Join(root, projectPath)
+		}
+		fileEntries, err := readLexiconEntries(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	entries = append(entries, project.Pronunciations...)
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return NewLexicon(entries)
+}
+
+// Apply rewrites text using every rule in order. A nil Lexicon is a no-op.
+func (l *Lexicon) Apply(text string) string {
+	if l == nil {
+		return text
+	}
+	for _, r := range l.rules {
+		if r.literal {
+			text = r.re.ReplaceAllLiteralString(text, r.say)
+		} else {
+			text = r.re.ReplaceAllString(text, r.say)
+		}
+	}
+	return text
+}
+
+func readLexiconEntries(path string) ([]LexiconEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("lexicon: %w", err)
+	}
+	var f lexiconFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("lexicon %s: %w", path, err)
+	}
+	if len(f.Entries) == 0 {
+		return nil, fmt.Errorf("lexicon %s: no entries", path)
+	}
+	return f.Entries, nil
+}
+
+// wordBoundary returns `\b` when the start (or end) of word is an ASCII word
+// character, so that "C++" or ".NET" still match next to punctuation. RE2's
+// `\b` is ASCII-only, so other scripts fall back to no boundary.
+func wordBoundary(word string, start bool) string {
+	var r rune
+	if start {
+		r, _ = utf8.DecodeRuneInString(word)
+	} else {
+		r, _ = utf8.DecodeLastRuneInString(word)
+	}
+	if r < utf8.RuneSelf && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return `\b`
+	}
+	return ""
+}
+
+// fingerprint summarises the rules for change detection; nil yields "".
+func (l *Lexicon) fingerprint() string {
+	if l == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, r := range l.rules {
+		fmt.Fprintf(&b, "%s\x00%s\x00%t\x00", r.re.String(), r.say, r.literal)
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLexiconApply(t *testing.T) {
+	lex, err := NewLexicon([]LexiconEntry{
+		{Match: "nginx", Say: "engine x"},
+		{Match: "SQL", Say: "sequel", CaseSensitive: true},
+		{Match: "C++", Say: "C plus plus"},
+		{Match: `\bv(\d+)\b`, Say: "version $1", Regex: true},
+	})
+	if err != nil {
+		t.Fatalf("NewLexicon error: %v", err)
+	}
+
+	got := lex.Apply("Nginx fronts nginxish SQL and sql apps in C++, see v2.")
+	want := "engine x fronts nginxish sequel and sql apps in C plus plus, see version 2."
+	if got != want {
+		t.Fatalf("Apply() = %q, want %q", got, want)
+	}
+}
+
+func TestLexiconNilIsNoop(t *testing.T) {
+	var lex *Lexicon
+	if got := lex.Apply("unchanged"); got != "unchanged" {
+		t.Fatalf("nil lexicon changed text: %q", got)
+	}
+}
+
+func TestResolveLexiconMergesProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	cliPath := filepath.Join(root, "cli.yaml")
+	if err := os.WriteFile(cliPath, []byte("entries:\n  - match: k8s\n    say: kubernetes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "team.yaml"), []byte("entries:\n  - match: k8s\n    say: kates\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	project := "lexicon: team.yaml\npronunciations:\n  - match: GUI\n    say: gooey\n"
+	if err := os.WriteFile(filepath.Join(root, ProjectConfigName), []byte(project), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lex, err := ResolveLexicon(root, cliPath)
+	if err != nil {
+		t.Fatalf("ResolveLexicon error: %v", err)
+	}
+	if got, want := lex.Apply("k8s GUI"), "kubernetes gooey"; got != want {
+		t.Fatalf("Apply() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveLexiconEmpty(t *testing.T) {
+	lex, err := ResolveLexicon(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("ResolveLexicon error: %v", err)
+	}
+	if lex != nil {
+		t.Fatalf("expected nil lexicon, got %+v", lex)
+	}
+}
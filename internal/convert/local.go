@@ -0,0 +1,113 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// localEngine is a TTS engine installed on this machine that reads text on
+// stdin and writes WAV to stdout. Local engines need no network access and no
+// API key.
+type localEngine struct {
+	info ProviderInfo
+	// binary is the executable looked up on PATH when no path is given.
+	binary string
+	// args returns the arguments for one chunk, after the executable.
+	args func(cfg Config) ([]string, error)
+	// oneLine joins a chunk into a single line before it is written to the
+	// engine, for engines that synthesize each input line separately.
+	oneLine bool
+}
+
+// localEngines lists the engines registered as providers of the same name.
+var localEngines = []localEngine{
+	{
+		info: ProviderInfo{
+			Name:          "piper",
+			Description:   "Piper neural TTS with a local ONNX voice model",
+			DefaultFormat: "wav",
+			Local:         true,
+		},
+		binary: "piper",
+		args: func(cfg Config) ([]string, error) {
+			if cfg.Voice == "" {
+				return nil, errors.New("piper: no voice model; pass -voice /path/to/voice.onnx")
+			}
+			return []string{
+				"--model", cfg.Voice,
+				"--output_file", "-",
+				"--length_scale", formatSpeed(1 / speedOf(cfg)),
+			}, nil
+		},
+		// piper writes one WAV file per line of input.
+		oneLine: true,
+	},
+	{
+		info: ProviderInfo{
+			Name:          "espeak-ng",
+			Description:   "eSpeak NG formant synthesizer",
+			DefaultVoice:  "en",
+			DefaultFormat: "wav",
+			Local:         true,
+		},
+		binary: "espeak-ng",
+		args: func(cfg Config) ([]string, error) {
+			args := []string{"--stdout", "-s", strconv.Itoa(int(175*speedOf(cfg) + 0.5))}
+			if cfg.Voice != "" {
+				args = append(args, "-v", cfg.Voice)
+			}
+			return args, nil
+		},
+	},
+	{
+		info: ProviderInfo{
+			Name:          "festival",
+			Description:   "Festival speech synthesis via text2wave",
+			DefaultFormat: "wav",
+			Local:         true,
+		},
+		binary: "text2wave",
+		args: func(cfg Config) ([]string, error) {
+			args := []string{"-o", "-"}
+			if cfg.Voice != "" {
+				args = append(args, "-eval", fmt.Sprintf("(voice_%s)", cfg.Voice))
+			}
+			if speed := speedOf(cfg); speed != 1 {
+				args = append(args, "-eval", fmt.Sprintf("(Parameter.set 'Duration_Stretch %s)", formatSpeed(1/speed)))
+			}
+			return args, nil
+		},
+	},
+}
+
+// client returns a client that runs the engine's executable, or binary when
+// it is not empty.
+func (e localEngine) client(binary string) TTSClient {
+	if binary == "" {
+		binary = e.binary
+	}
+	c := &commandClient{
+		format: "wav",
+		argv: func(cfg Config) ([]string, error) {
+			args, err := e.args(cfg)
+			if err != nil {
+				return nil, err
+			}
+			return append([]string{binary}, args...), nil
+		},
+	}
+	if e.oneLine {
+		c.input = func(chunk string) string { return strings.Join(strings.Fields(chunk), " ") }
+	}
+	return c
+}
+
+func init() {
+	for _, e := range localEngines {
+		RegisterProvider(e.info, func(pc ProviderConfig) (TTSClient, error) {
+			return e.client(pc.Command), nil
+		})
+	}
+}
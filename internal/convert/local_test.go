@@ -0,0 +1,132 @@
+package convert
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeEngine writes a shell script that records its arguments and stdin and
+// prints a WAV file, standing in for a local TTS engine.
+func fakeEngine(t *testing.T) (path string, args func() []string, input func() string) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	path = filepath.Join(dir, "engine")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$0.args\"\ncat >> \"$0.text\"\ncat \"$0.wav\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".wav", wavFile([]byte{1, 2}, 0xFFFFFFFF), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args = func() []string {
+		b, _ := os.ReadFile(path + ".args")
+		return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	}
+	input = func() string {
+		b, _ := os.ReadFile(path + ".text")
+		return string(b)
+	}
+	return path, args, input
+}
+
+func TestLocalEngineArguments(t *testing.T) {
+	engine, args, _ := fakeEngine(t)
+	tests := []struct {
+		provider string
+		cfg      Config
+		want     []string
+	}{
+		{"piper", Config{Voice: "en_US-amy.onnx", Speed: 1.25}, []string{"--model", "en_US-amy.onnx", "--output_file", "-", "--length_scale", "0.8"}},
+		{"espeak-ng", Config{Voice: "en-us", Speed: 2}, []string{"--stdout", "-s", "350", "-v", "en-us"}},
+		{"festival", Config{Voice: "kal_diphone"}, []string{"-o", "-", "-eval", "(voice_kal_diphone)"}},
+	}
+	for _, tt := range tests {
+		client, err := NewTTSClient(tt.provider, ProviderConfig{Command: engine})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FormatsOf(client); !slices.Equal(got, []string{"wav"}) {
+			t.Errorf("%s formats = %v", tt.provider, got)
+		}
+		if _, err := client.Synthesize(context.Background(), tt.cfg, "Hello."); err != nil {
+			t.Fatalf("%s: %v", tt.provider, err)
+		}
+		if got := args(); !slices.Equal(got, tt.want) {
+			t.Errorf("%s args = %q, want %q", tt.provider, got, tt.want)
+		}
+	}
+
+	piper, _ := NewTTSClient("piper", ProviderConfig{Command: engine})
+	if _, err := piper.Synthesize(context.Background(), Config{}, "Hello."); err == nil {
+		t.Error("piper without a voice model should fail")
+	}
+	missing, _ := NewTTSClient("espeak-ng", ProviderConfig{Command: filepath.Join(t.TempDir(), "espeak-ng")})
+	if _, err := missing.Synthesize(context.Background(), Config{}, "Hello."); err == nil {
+		t.Error("missing engine should fail")
+	}
+}
+
+func TestPiperReadsOneLinePerChunk(t *testing.T) {
+	engine, _, input := fakeEngine(t)
+	// piper prints one WAV per input line; the client must keep them all.
+	twoLines := append(wavFile([]byte{1, 2}, 2), wavFile([]byte{3, 4}, 2)...)
+	if err := os.WriteFile(engine+".wav", twoLines, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	piper, err := NewTTSClient("piper", ProviderConfig{Command: engine})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := piper.Synthesize(context.Background(), Config{Voice: "amy.onnx"}, "One.\n\n- two\n- three")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := input(); got != "One. - two - three" {
+		t.Errorf("piper read %q, want a single line", got)
+	}
+	joined, err := JoinAudio("wav", [][]byte{audio})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := parseWAV(joined); err != nil || string(data) != "\x01\x02\x03\x04" {
+		t.Fatalf("joined wav data = %v, %v", data, err)
+	}
+}
+
+func TestProcessFileWithLocalEngine(t *testing.T) {
+	engine, _, input := fakeEngine(t)
+	cfg := Config{Provider: "espeak-ng", ResponseFormat: "wav", SectionLevel: 1, ChunkSize: 22}
+	conv, err := NewConverter(cfg, WithProviderConfig(ProviderConfig{Command: engine}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	src := filepath.Join(root, "note.md")
+	if err := os.WriteFile(src, []byte("# One\n\nFirst.\n\n# Two\n\nSecond."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job := FileJob{AbsPath: src, DestPath: filepath.Join(root, "out", "note.wav")}
+	res := conv.ProcessFile(context.Background(), job, nil)
+	if res.Status != JobDone || res.Chunks != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	if got := input(); !strings.Contains(got, "First.") || !strings.Contains(got, "Second.") {
+		t.Fatalf("engine read %q", got)
+	}
+	audio, err := os.ReadFile(job.DestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := parseWAV(audio); err != nil || string(data) != "\x01\x02\x01\x02" {
+		t.Fatalf("joined wav data = %v, %v", data, err)
+	}
+}
@@ -0,0 +1,95 @@
+package convert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ManifestSuffix is appended to an audio file's path to name its sidecar
+// manifest.
+const ManifestSuffix = ".markloud.json"
+
+// Manifest records what an audio file was generated from, so that update runs
+// can tell whether the source or the synthesis settings have changed since.
+type Manifest struct {
+	Source       string    `json:"source"`
+	SourceHash   string    `json:"source_hash"`
+	SettingsHash string    `json:"settings_hash"`
+	Generated    time.Time `json:"generated"`
+}
+
+// ManifestPath returns the sidecar manifest path for an audio file.
+func ManifestPath(dest string) string {
+	return dest + ManifestSuffix
+}
+
+// ReadManifest loads the manifest stored beside dest.
+func ReadManifest(dest string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(ManifestPath(dest))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("manifest %s: %w", ManifestPath(dest), err)
+	}
+	return m, nil
+}
+
+// writeManifest stores m beside dest.
+func writeManifest(dest string, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ManifestPath(dest), append(data, '\n'), 0o644)
+}
+
+// upToDate reports whether the manifest beside dest matches the given hashes.
+// A missing or unreadable manifest counts as out of date.
+func upToDate(dest, sourceHash, settingsHash string) bool {
+	m, err := ReadManifest(dest)
+	return err == nil && m.SourceHash == sourceHash && m.SettingsHash == settingsHash
+}
+
+// hashBytes returns the hex SHA-256 of data.
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// settingsHash hashes every setting that changes the spoken text or the audio
+// for a file, after front matter overrides have been applied.
+func settingsHash(cfg Config) string {
+	provider := cfg.Provider
+	if provider == "" {
+		provider = DefaultProvider
+	}
+	parts := []string{
+		provider, cfg.Model, cfg.Voice, strconv.FormatFloat(cfg.Speed, 'g', -1, 64),
+		cfg.Instructions, cfg.ResponseFormat,
+		string(cfg.Tables), strconv.Itoa(cfg.TableMaxRows), string(cfg.CodeBlocks),
+		cfg.Locale, string(cfg.URLs), strconv.Itoa(cfg.ChunkSize), strconv.Itoa(cfg.SectionLevel),
+		cfg.Lexicon.fingerprint(),
+	}
+	levels := make([]int, 0, len(cfg.HeadingCues))
+	for level := range cfg.HeadingCues {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+	for _, level := range levels {
+		parts = append(parts, strconv.Itoa(level), cfg.HeadingCues[level])
+	}
+
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
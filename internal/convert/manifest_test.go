@@ -0,0 +1,72 @@
+package convert
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessFileUpdateMode(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	if err := os.WriteFile(src, []byte("Hello."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job := FileJob{AbsPath: src, RelPath: "file.md", DestPath: filepath.Join(root, "out", "file.aac")}
+	cfg := Config{ResponseFormat: "aac", Voice: "alloy", Update: true}
+
+	mock := &mockTTSClient{resp: []byte("A")}
+	conv := newTestConverter(t, cfg, mock)
+
+	if res := conv.ProcessFile(context.Background(), job, nil); res.Status != JobDone {
+		t.Fatalf("first run = %+v", res)
+	}
+	m, err := ReadManifest(job.DestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Source != "file.md" || m.SourceHash == "" || m.SettingsHash == "" || m.Generated.IsZero() {
+		t.Fatalf("incomplete manifest: %+v", m)
+	}
+
+	if res := conv.ProcessFile(context.Background(), job, nil); res.Status != JobSkipped {
+		t.Fatalf("unchanged file should be skipped, got %v", res.Status)
+	}
+
+	changed := cfg
+	changed.Voice = "nova"
+	conv = newTestConverter(t, changed, mock)
+	if res := conv.ProcessFile(context.Background(), job, nil); res.Status != JobDone {
+		t.Fatalf("settings change should regenerate, got %v", res.Status)
+	}
+
+	if err := os.WriteFile(src, []byte("Hello again."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if res := conv.ProcessFile(context.Background(), job, nil); res.Status != JobDone {
+		t.Fatalf("source change should regenerate, got %v", res.Status)
+	}
+	if mock.calls != 3 {
+		t.Fatalf("synthesized %d times, want 3", mock.calls)
+	}
+
+	if err := os.Remove(ManifestPath(job.DestPath)); err != nil {
+		t.Fatal(err)
+	}
+	if res := conv.ProcessFile(context.Background(), job, nil); res.Status != JobDone {
+		t.Fatalf("missing manifest should regenerate, got %v", res.Status)
+	}
+}
+
+func TestSettingsHashIgnoresCueOrder(t *testing.T) {
+	a := Config{HeadingCues: map[int]string{1: "Chapter: {title}.", 2: "Section: {title}."}}
+	b := Config{HeadingCues: map[int]string{2: "Section: {title}.", 1: "Chapter: {title}."}}
+	if settingsHash(a) != settingsHash(b) {
+		t.Fatal("settingsHash depends on map order")
+	}
+	b.Tables = TableSkip
+	if settingsHash(a) == settingsHash(b) {
+		t.Fatal("table mode should change the settings hash")
+	}
+}
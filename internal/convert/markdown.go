@@ -0,0 +1,536 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// markdownParser understands CommonMark plus the GFM extensions and footnotes.
+var markdownParser = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+).Parser()
+
+// TableMode selects how GFM tables are read aloud.
+type TableMode string
+
+const (
+	// TableRows reads each body row as "Column: value, Column: value".
+	TableRows TableMode = "rows"
+	// TableCells reads every row, header included, as its cells joined by commas.
+	TableCells TableMode = "cells"
+	// TableSkip replaces the table with a short "table with N rows omitted" note.
+	TableSkip TableMode = "skip"
+)
+
+// ParseTableMode validates a table mode name; the empty string selects TableRows.
+func ParseTableMode(s string) (TableMode, error) {
+	switch mode := TableMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return TableRows, nil
+	case TableRows, TableCells, TableSkip:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown table mode %q (want rows, cells or skip)", s)
+	}
+}
+
+// CodeBlockMode selects how fenced and indented code blocks are spoken.
+type CodeBlockMode string
+
+const (
+	// CodeDrop removes code blocks from the spoken text.
+	CodeDrop CodeBlockMode = "drop"
+	// CodeAnnounce replaces a block with e.g. "Code block in Go, 12 lines, skipped."
+	CodeAnnounce CodeBlockMode = "announce"
+	// CodeVerbatim reads the code exactly as written.
+	CodeVerbatim CodeBlockMode = "verbatim"
+	// CodeComments reads only the comments in the block, announcing it when it has none.
+	CodeComments CodeBlockMode = "comments"
+)
+
+// ParseCodeBlockMode validates a code block mode name; the empty string selects CodeDrop.
+func ParseCodeBlockMode(s string) (CodeBlockMode, error) {
+	switch mode := CodeBlockMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return CodeDrop, nil
+	case CodeDrop, CodeAnnounce, CodeVerbatim, CodeComments:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown code block mode %q (want drop, announce, verbatim or comments)", s)
+	}
+}
+
+// DefaultSectionLevel is the deepest heading level that starts a new
+// section when Config.SectionLevel is unset.
+const DefaultSectionLevel = 2
+
+// Section is a run of speakable paragraphs under one top-level heading.
+type Section struct {
+	// Heading is the heading text, empty for content before the first heading.
+	Heading string
+	// Level is the heading level (1-6), or 0 when Heading is empty.
+	Level int
+	// Paragraphs holds the spoken text, starting with the heading cue if any.
+	Paragraphs []string
+	// lead counts paragraphs spoken before the heading cue, such as a
+	// document title.
+	lead int
+}
+
+// Text joins the section's paragraphs with blank lines.
+func (s Section) Text() string {
+	return strings.Join(s.Paragraphs, "\n\n")
+}
+
+// StripMarkdown renders Markdown as plain prose suitable for TTS input using
+// the default rendering options.
+func StripMarkdown(md string) string {
+	return SpeakableText(md, Config{})
+}
+
+// SpeakableText renders Markdown as plain prose suitable for TTS input.
+//
+// The document is parsed into a CommonMark/GFM tree and each node type is
+// rendered to speakable text: markup is dropped, blocks are separated by
+// blank lines and list items each get their own line. Table handling follows
+// cfg.Tables and cfg.TableMaxRows; code blocks follow cfg.CodeBlocks.
+func SpeakableText(md string, cfg Config) string {
+	var paras []string
+	for _, sec := range SpeakableSections(md, cfg) {
+		paras = append(paras, sec.Paragraphs...)
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+// SpeakableSections renders Markdown like SpeakableText but keeps the
+// document split at top-level headings of cfg.SectionLevel or shallower, so
+// chunks can be aligned with the document structure. Headings are spoken
+// using cfg.HeadingCues.
+func SpeakableSections(md string, cfg Config) []Section {
+	source := []byte(md)
+	doc := markdownParser.Parse(text.NewReader(source))
+	r := &speechRenderer{source: source, cfg: cfg}
+
+	level := cfg.SectionLevel
+	if level <= 0 {
+		level = DefaultSectionLevel
+	}
+	var sections []Section
+	current := Section{}
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		if h, ok := c.(*ast.Heading); ok && h.Level <= level {
+			if current.Heading != "" || len(current.Paragraphs) > 0 {
+				sections = append(sections, current)
+			}
+			current = Section{Heading: r.inline(h), Level: h.Level}
+		}
+		current.Paragraphs = append(current.Paragraphs, r.block(c)...)
+	}
+	if current.Heading != "" || len(current.Paragraphs) > 0 {
+		sections = append(sections, current)
+	}
+	return sections
+}
+
+// ParseHeadingCue parses a "LEVEL=TEMPLATE" flag value such as
+// "2=Section: {title}.".
+func ParseHeadingCue(s string) (int, string, error) {
+	lvl, tmpl, ok := strings.Cut(s, "=")
+	level, err := strconv.Atoi(strings.TrimSpace(lvl))
+	if !ok || err != nil || level < 1 || level > 6 {
+		return 0, "", fmt.Errorf("invalid heading cue %q (want LEVEL=TEMPLATE with LEVEL 1-6)", s)
+	}
+	return level, tmpl, nil
+}
+
+// speechRenderer walks a parsed Markdown tree and produces speakable text.
+type speechRenderer struct {
+	source []byte
+	cfg    Config
+}
+
+// blocks renders every block child of n, skipping ones that produce no speech.
+func (r *speechRenderer) blocks(n ast.Node) []string {
+	var out []string
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		out = append(out, r.block(c)...)
+	}
+	return out
+}
+
+// block renders a single block node as zero or more paragraphs of speech.
+func (r *speechRenderer) block(n ast.Node) []string {
+	switch n := n.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return nonEmpty(r.inline(n))
+	case *ast.Heading:
+		return nonEmpty(r.headingCue(n))
+	case *ast.Blockquote:
+		return r.blocks(n)
+	case *ast.List:
+		return nonEmpty(strings.Join(r.listItems(n), "\n"))
+	case *east.Table:
+		return nonEmpty(strings.Join(r.table(n), "\n"))
+	case *east.FootnoteList:
+		return r.footnotes(n)
+	case *ast.FencedCodeBlock:
+		return r.codeBlock(n, string(n.Language(r.source)))
+	case *ast.CodeBlock:
+		return r.codeBlock(n, "")
+	case *ast.HTMLBlock, *ast.ThematicBreak:
+		return nil
+	default:
+		return r.blocks(n)
+	}
+}
+
+// headingCue renders a heading through the template configured for its
+// level, where "{title}" stands for the heading text.
+func (r *speechRenderer) headingCue(h *ast.Heading) string {
+	title := r.inline(h)
+	tmpl, ok := r.cfg.HeadingCues[h.Level]
+	if !ok || title == "" {
+		return title
+	}
+	return strings.TrimSpace(strings.ReplaceAll(tmpl, "{title}", title))
+}
+
+// listItems renders each item of a list on its own line, flattening nested lists.
+func (r *speechRenderer) listItems(list *ast.List) []string {
+	var lines []string
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+			if nested, ok := c.(*ast.List); ok {
+				lines = append(lines, r.listItems(nested)...)
+				continue
+			}
+			lines = append(lines, r.block(c)...)
+		}
+	}
+	return lines
+}
+
+// table renders a GFM table according to the configured TableMode.
+func (r *speechRenderer) table(table *east.Table) []string {
+	var header []string
+	var rows [][]string
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, r.inline(cell))
+		}
+		if _, ok := row.(*east.TableHeader); ok {
+			header = cells
+			continue
+		}
+		rows = append(rows, cells)
+	}
+
+	mode, err := ParseTableMode(string(r.cfg.Tables))
+	if err != nil {
+		mode = TableRows
+	}
+	if mode == TableSkip {
+		return []string{fmt.Sprintf("Table with %s omitted.", plural(len(rows), "row"))}
+	}
+
+	var lines []string
+	if mode == TableCells {
+		if line := joinNonEmpty(header, ", "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	omitted := 0
+	if limit := r.cfg.TableMaxRows; limit > 0 && len(rows) > limit {
+		omitted = len(rows) - limit
+		rows = rows[:limit]
+	}
+	for _, cells := range rows {
+		if mode == TableRows {
+			labeled := make([]string, len(cells))
+			for i, v := range cells {
+				if v != "" && i < len(header) && header[i] != "" {
+					v = header[i] + ": " + v
+				}
+				labeled[i] = v
+			}
+			cells = labeled
+		}
+		if line := joinNonEmpty(cells, ", "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if omitted > 0 {
+		lines = append(lines, fmt.Sprintf("%s omitted.", plural(omitted, "more table row")))
+	}
+	return lines
+}
+
+// codeBlock renders a fenced or indented code block according to cfg.CodeBlocks.
+// lang is the first word of the fence info string, if any.
+func (r *speechRenderer) codeBlock(n ast.Node, lang string) []string {
+	segments := n.Lines()
+	lines := make([]string, 0, segments.Len())
+	for i := 0; i < segments.Len(); i++ {
+		seg := segments.At(i)
+		lines = append(lines, strings.TrimRight(string(seg.Value(r.source)), "\r\n"))
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	mode, err := ParseCodeBlockMode(string(r.cfg.CodeBlocks))
+	if err != nil {
+		mode = CodeDrop
+	}
+	subject := "Code block"
+	if name := languageName(lang); name != "" {
+		subject += " in " + name
+	}
+
+	switch mode {
+	case CodeAnnounce:
+		return []string{fmt.Sprintf("%s, %s, skipped.", subject, plural(len(lines), "line"))}
+	case CodeVerbatim:
+		return nonEmpty(strings.TrimSpace(strings.Join(lines, "\n")))
+	case CodeComments:
+		comments := codeComments(lines, lang)
+		if len(comments) == 0 {
+			return []string{fmt.Sprintf("%s, %s, skipped.", subject, plural(len(lines), "line"))}
+		}
+		return []string{subject + ". " + strings.Join(comments, "\n")}
+	default:
+		return nil
+	}
+}
+
+// footnotes renders the collected footnote definitions at the end of the document.
+func (r *speechRenderer) footnotes(list *east.FootnoteList) []string {
+	var out []string
+	for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+		fn, ok := c.(*east.Footnote)
+		if !ok {
+			continue
+		}
+		body := strings.Join(r.blocks(fn), " ")
+		if body == "" {
+			continue
+		}
+		out = append(out, fmt.Sprintf("Footnote %d: %s", fn.Index, body))
+	}
+	return out
+}
+
+// inline renders the inline children of n as a single cleaned-up string.
+func (r *speechRenderer) inline(n ast.Node) string {
+	var b strings.Builder
+	r.writeInline(&b, n)
+	return cleanLines(b.String())
+}
+
+func (r *speechRenderer) writeInline(b *strings.Builder, n ast.Node) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			value := c.Value(r.source)
+			if !c.IsRaw() {
+				value = unescapeMarkdown(value)
+			}
+			b.Write(value)
+			switch {
+			case c.HardLineBreak():
+				b.WriteByte('\n')
+			case c.SoftLineBreak():
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		case *ast.CodeSpan:
+			for t := c.FirstChild(); t != nil; t = t.NextSibling() {
+				switch t := t.(type) {
+				case *ast.Text:
+					b.Write(t.Value(r.source))
+				case *ast.String:
+					b.Write(t.Value)
+				}
+			}
+		case *ast.AutoLink:
+			b.Write(c.Label(r.source))
+		case *ast.RawHTML:
+			// Tags are not spoken, but a line break still separates words.
+			if r.isLineBreakTag(c) && b.Len() > 0 && !strings.HasSuffix(b.String(), " ") {
+				b.WriteByte(' ')
+			}
+		case *east.FootnoteLink, *east.FootnoteBacklink, *east.TaskCheckBox:
+			// Markup with no spoken form.
+		default:
+			r.writeInline(b, c)
+		}
+	}
+}
+
+// brTagRe matches an HTML line break tag such as <br> or <br />.
+var brTagRe = regexp.MustCompile(`(?i)^<br\s*/?>$`)
+
+// isLineBreakTag reports whether raw is an HTML <br> tag.
+func (r *speechRenderer) isLineBreakTag(raw *ast.RawHTML) bool {
+	var tag strings.Builder
+	for i := 0; i < raw.Segments.Len(); i++ {
+		seg := raw.Segments.At(i)
+		tag.Write(seg.Value(r.source))
+	}
+	return brTagRe.MatchString(strings.TrimSpace(tag.String()))
+}
+
+// languageNames maps common fence info strings to their spoken names.
+var languageNames = map[string]string{
+	"bash":       "Bash",
+	"c":          "C",
+	"c++":        "C plus plus",
+	"cpp":        "C plus plus",
+	"cs":         "C sharp",
+	"csharp":     "C sharp",
+	"css":        "CSS",
+	"diff":       "diff",
+	"go":         "Go",
+	"golang":     "Go",
+	"html":       "HTML",
+	"java":       "Java",
+	"javascript": "JavaScript",
+	"js":         "JavaScript",
+	"json":       "JSON",
+	"kotlin":     "Kotlin",
+	"lua":        "Lua",
+	"make":       "Makefile",
+	"makefile":   "Makefile",
+	"php":        "PHP",
+	"py":         "Python",
+	"python":     "Python",
+	"rb":         "Ruby",
+	"ruby":       "Ruby",
+	"rs":         "Rust",
+	"rust":       "Rust",
+	"sh":         "shell",
+	"shell":      "shell",
+	"sql":        "SQL",
+	"swift":      "Swift",
+	"toml":       "TOML",
+	"ts":         "TypeScript",
+	"typescript": "TypeScript",
+	"yaml":       "YAML",
+	"yml":        "YAML",
+	"zsh":        "shell",
+}
+
+// languageName returns the spoken name for a fence language tag.
+func languageName(lang string) string {
+	lang = strings.TrimSpace(lang)
+	if name, ok := languageNames[strings.ToLower(lang)]; ok {
+		return name
+	}
+	return lang
+}
+
+// commentPrefixes lists line-comment markers by fence language. Languages not
+// listed accept both "//" and "#".
+var commentPrefixes = map[string][]string{
+	"bash": {"#"}, "sh": {"#"}, "shell": {"#"}, "zsh": {"#"},
+	"py": {"#"}, "python": {"#"}, "rb": {"#"}, "ruby": {"#"},
+	"yaml": {"#"}, "yml": {"#"}, "toml": {"#"}, "make": {"#"}, "makefile": {"#"},
+	"sql": {"--"}, "lua": {"--"}, "haskell": {"--"},
+	"go": {"//"}, "golang": {"//"}, "c": {"//"}, "cpp": {"//"}, "c++": {"//"},
+	"cs": {"//"}, "csharp": {"//"}, "java": {"//"}, "js": {"//"}, "javascript": {"//"},
+	"ts": {"//"}, "typescript": {"//"}, "rs": {"//"}, "rust": {"//"},
+	"kotlin": {"//"}, "swift": {"//"}, "php": {"//", "#"},
+}
+
+// codeComments extracts whole-line comments (and /* */ block comment bodies)
+// from code, stripped of their markers.
+func codeComments(lines []string, lang string) []string {
+	prefixes, ok := commentPrefixes[strings.ToLower(strings.TrimSpace(lang))]
+	if !ok {
+		prefixes = []string{"//", "#"}
+	}
+	var out []string
+	inBlock := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if inBlock || strings.HasPrefix(line, "/*") {
+			end := strings.Contains(line, "*/")
+			line = strings.TrimPrefix(line, "/*")
+			line, _, _ = strings.Cut(line, "*/")
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*"))
+			inBlock = !end
+		} else {
+			matched := false
+			for _, p := range prefixes {
+				if strings.HasPrefix(line, p) && !strings.HasPrefix(line, "#!") {
+					line = strings.TrimSpace(strings.TrimLeft(line, p[:1]))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// unescapeMarkdown resolves backslash escapes and HTML entities in literal text.
+func unescapeMarkdown(v []byte) []byte {
+	v = util.UnescapePunctuations(v)
+	v = util.ResolveNumericReferences(v)
+	return util.ResolveEntityNames(v)
+}
+
+// cleanLines collapses runs of whitespace and drops empty lines.
+func cleanLines(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// joinNonEmpty joins the non-empty values of vs with sep.
+func joinNonEmpty(vs []string, sep string) string {
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// plural formats a count with a naively pluralised noun ("1 row", "3 rows").
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
@@ -0,0 +1,98 @@
+package convert
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// TestStripMarkdownGolden renders every testdata/speech/*.md file and compares
+// it with the matching .txt file. Run with -update to regenerate the corpus.
+func TestStripMarkdownGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "speech", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden inputs found")
+	}
+	for _, in := range inputs {
+		name := strings.TrimSuffix(filepath.Base(in), ".md")
+		t.Run(name, func(t *testing.T) {
+			md, err := os.ReadFile(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := StripMarkdown(string(md)) + "\n"
+			goldenPath := strings.TrimSuffix(in, ".md") + ".txt"
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Fatalf("StripMarkdown(%s) mismatch\n got: %q\nwant: %q", in, got, string(want))
+			}
+		})
+	}
+}
+
+func TestSpeakableTextTableModes(t *testing.T) {
+	md := "| Name | Role |\n|---|---|\n| Ada | lead |\n| Bob | dev |\n| Cy | qa |\n"
+
+	cases := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"rows", Config{}, "Name: Ada, Role: lead\nName: Bob, Role: dev\nName: Cy, Role: qa"},
+		{"cells", Config{Tables: TableCells}, "Name, Role\nAda, lead\nBob, dev\nCy, qa"},
+		{"skip", Config{Tables: TableSkip}, "Table with 3 rows omitted."},
+		{"capped", Config{TableMaxRows: 1}, "Name: Ada, Role: lead\n2 more table rows omitted."},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SpeakableText(md, tc.cfg); got != tc.want {
+				t.Fatalf("SpeakableText() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSpeakableTextCodeBlockModes(t *testing.T) {
+	md := "Run the following:\n\n```go\n// Load settings from disk.\ncfg := load()\n/* Retry\n * on failure. */\nrun(cfg)\n```\n"
+
+	cases := []struct {
+		name string
+		mode CodeBlockMode
+		want string
+	}{
+		{"drop", "", "Run the following:"},
+		{"announce", CodeAnnounce, "Run the following:\n\nCode block in Go, 5 lines, skipped."},
+		{"verbatim", CodeVerbatim, "Run the following:\n\n// Load settings from disk.\ncfg := load()\n/* Retry\n * on failure. */\nrun(cfg)"},
+		{"comments", CodeComments, "Run the following:\n\nCode block in Go. Load settings from disk.\nRetry\non failure."},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SpeakableText(md, Config{CodeBlocks: tc.mode}); got != tc.want {
+				t.Fatalf("SpeakableText() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSpeakableTextCodeCommentsFallsBackToAnnounce(t *testing.T) {
+	md := "```\nmake build\n```\n"
+	want := "Code block, 1 line, skipped."
+	if got := SpeakableText(md, Config{CodeBlocks: CodeComments}); got != want {
+		t.Fatalf("SpeakableText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,526 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// audioMuxer joins the audio of consecutive chunks into a single valid file.
+// Each chunk arrives as a complete file in the run's response format.
+type audioMuxer interface {
+	// Add appends one chunk's encoded audio.
+	Add(chunk []byte) error
+	// Finish writes trailing data and patches headers once all chunks are added.
+	Finish() error
+}
+
+// muxFormats lists the response formats newAudioMuxer can join.
+var muxFormats = []string{"mp3", "opus", "aac", "flac", "wav", "pcm"}
+
+// newAudioMuxer returns a muxer writing format to w. Formats whose headers
+// record the total length (wav) seek back in w when finishing.
+func newAudioMuxer(format string, w io.WriteSeeker) (audioMuxer, error) {
+	switch strings.ToLower(format) {
+	case "", "aac", "pcm": // empty means the historical aac default
+		// ADTS frames and raw PCM samples can be appended as-is.
+		return &rawMuxer{w: w}, nil
+	case "mp3":
+		return &mp3Muxer{w: w}, nil
+	case "wav":
+		return &wavMuxer{w: w}, nil
+	case "flac":
+		return &flacMuxer{w: w}, nil
+	case "opus":
+		return &oggMuxer{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported response format %q", format)
+	}
+}
+
+// JoinAudio concatenates per-chunk audio files of the given format into one.
+func JoinAudio(format string, chunks [][]byte) ([]byte, error) {
+	var buf memFile
+	mux, err := newAudioMuxer(format, &buf)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		if err := mux.Add(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := mux.Finish(); err != nil {
+		return nil, err
+	}
+	return buf.data, nil
+}
+
+type rawMuxer struct {
+	w io.Writer
+}
+
+func (m *rawMuxer) Add(chunk []byte) error {
+	_, err := m.w.Write(chunk)
+	return err
+}
+
+func (m *rawMuxer) Finish() error { return nil }
+
+// mp3Muxer drops ID3 tags and Xing/Info/VBRI header frames, which describe a
+// single chunk and would be wrong (or mid-file) in the joined stream.
+type mp3Muxer struct {
+	w io.Writer
+}
+
+func (m *mp3Muxer) Add(chunk []byte) error {
+	chunk = stripID3(chunk)
+	if n := mp3FrameLen(chunk); n > 0 && n <= len(chunk) && isVBRHeaderFrame(chunk[:n]) {
+		chunk = chunk[n:]
+	}
+	_, err := m.w.Write(chunk)
+	return err
+}
+
+func (m *mp3Muxer) Finish() error { return nil }
+
+// stripID3 removes a leading ID3v2 tag and a trailing ID3v1 tag.
+func stripID3(b []byte) []byte {
+	if len(b) >= 10 && string(b[:3]) == "ID3" {
+		size := int(b[6]&0x7f)<<21 | int(b[7]&0x7f)<<14 | int(b[8]&0x7f)<<7 | int(b[9]&0x7f)
+		size += 10
+		if b[5]&0x10 != 0 {
+			size += 10 // footer present
+		}
+		if size > len(b) {
+			size = len(b)
+		}
+		b = b[size:]
+	}
+	if len(b) >= 128 && string(b[len(b)-128:len(b)-125]) == "TAG" {
+		b = b[:len(b)-128]
+	}
+	return b
+}
+
+var (
+	mp3BitratesV1 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3Rates      = map[byte][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+)
+
+// mp3FrameLen returns the length of the Layer III frame at the start of b,
+// or 0 if b does not start with a valid frame header.
+func mp3FrameLen(b []byte) int {
+	if len(b) < 4 || b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return 0
+	}
+	version := (b[1] >> 3) & 0x03
+	layer := (b[1] >> 1) & 0x03
+	rates, ok := mp3Rates[version]
+	if !ok || layer != 1 {
+		return 0
+	}
+	bitrateIdx := b[2] >> 4
+	rateIdx := (b[2] >> 2) & 0x03
+	if rateIdx == 3 {
+		return 0
+	}
+	padding := int(b[2]>>1) & 0x01
+	if version == 3 {
+		return 144*mp3BitratesV1[bitrateIdx]*1000/rates[rateIdx] + padding
+	}
+	return 72*mp3BitratesV2[bitrateIdx]*1000/rates[rateIdx] + padding
+}
+
+// isVBRHeaderFrame reports whether frame carries a Xing, Info or VBRI tag.
+func isVBRHeaderFrame(frame []byte) bool {
+	version := (frame[1] >> 3) & 0x03
+	mono := frame[3]>>6 == 3
+	side := 32
+	switch {
+	case version == 3 && mono:
+		side = 17
+	case version != 3 && mono:
+		side = 9
+	case version != 3:
+		side = 17
+	}
+	if off := 4 + side; len(frame) >= off+4 {
+		if tag := string(frame[off : off+4]); tag == "Xing" || tag == "Info" {
+			return true
+		}
+	}
+	return len(frame) >= 40 && string(frame[36:40]) == "VBRI"
+}
+
+// wavMuxer writes one RIFF header and the data chunks of every input,
+// patching the RIFF and data sizes when finished.
+type wavMuxer struct {
+	w          io.WriteSeeker
+	fmtChunk   []byte
+	dataOffset int64
+	dataLen    int64
+}
+
+func (m *wavMuxer) Add(chunk []byte) error {
+	fmtChunk, data, err := parseWAV(chunk)
+	if err != nil {
+		return err
+	}
+	if m.fmtChunk == nil {
+		m.fmtChunk = fmtChunk
+		var hdr bytes.Buffer
+		hdr.WriteString("RIFF")
+		binary.Write(&hdr, binary.LittleEndian, uint32(0))
+		hdr.WriteString("WAVEfmt ")
+		binary.Write(&hdr, binary.LittleEndian, uint32(len(fmtChunk)))
+		hdr.Write(fmtChunk)
+		if len(fmtChunk)%2 == 1 {
+			hdr.WriteByte(0)
+		}
+		hdr.WriteString("data")
+		binary.Write(&hdr, binary.LittleEndian, uint32(0))
+		if _, err := m.w.Write(hdr.Bytes()); err != nil {
+			return err
+		}
+		m.dataOffset = int64(hdr.Len())
+	} else if !bytes.Equal(m.fmtChunk, fmtChunk) {
+		return errors.New("wav: chunks use different sample formats")
+	}
+	n, err := m.w.Write(data)
+	m.dataLen += int64(n)
+	return err
+}
+
+func (m *wavMuxer) Finish() error {
+	if m.fmtChunk == nil {
+		return nil
+	}
+	end := m.dataOffset + m.dataLen
+	if m.dataLen%2 == 1 {
+		if _, err := m.w.Write([]byte{0}); err != nil {
+			return err
+		}
+		end++
+	}
+	if err := writeUint32At(m.w, 4, uint32(end-8)); err != nil {
+		return err
+	}
+	if err := writeUint32At(m.w, m.dataOffset-4, uint32(m.dataLen)); err != nil {
+		return err
+	}
+	_, err := m.w.Seek(end, io.SeekStart)
+	return err
+}
+
+// parseWAV returns the fmt chunk body and the sample data of a RIFF/WAVE
+// file. A data size that overruns the input (as streamed WAV headers often
+// declare) is taken to mean "until the end". Engines that write one file per
+// input line print several RIFF files back to back; their data is
+// concatenated, and they must share a sample format.
+func parseWAV(b []byte) (fmtChunk, data []byte, err error) {
+	if !isWAVHeader(b) {
+		return nil, nil, errors.New("wav: missing RIFF/WAVE header")
+	}
+	var fileFmt, fileData []byte
+	files := 0
+	// next folds the file read so far into the result.
+	next := func() error {
+		if fileFmt == nil || fileData == nil {
+			return errors.New("wav: missing fmt or data chunk")
+		}
+		switch {
+		case files == 0:
+			fmtChunk, data = fileFmt, fileData
+		case !bytes.Equal(fmtChunk, fileFmt):
+			return errors.New("wav: files use different sample formats")
+		default:
+			data = append(data[:len(data):len(data)], fileData...)
+		}
+		files++
+		fileFmt, fileData = nil, nil
+		return nil
+	}
+	for off := 12; off+8 <= len(b); {
+		if isWAVHeader(b[off:]) {
+			if err := next(); err != nil {
+				return nil, nil, err
+			}
+			off += 12
+			continue
+		}
+		id := string(b[off : off+4])
+		size := int(binary.LittleEndian.Uint32(b[off+4 : off+8]))
+		body := off + 8
+		if body+size > len(b) {
+			size = len(b) - body
+		}
+		switch id {
+		case "fmt ":
+			fileFmt = b[body : body+size]
+		case "data":
+			fileData = b[body : body+size]
+		}
+		off = body + size + size%2
+	}
+	if err := next(); err != nil {
+		return nil, nil, err
+	}
+	return fmtChunk, data, nil
+}
+
+// isWAVHeader reports whether b starts with a RIFF/WAVE header.
+func isWAVHeader(b []byte) bool {
+	return len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WAVE"
+}
+
+func writeUint32At(w io.WriteSeeker, off int64, v uint32) error {
+	if _, err := w.Seek(off, io.SeekStart); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, v)
+}
+
+// flacMuxer keeps the first chunk's metadata and appends the audio frames of
+// the rest. STREAMINFO fields that only described the first chunk (frame
+// sizes, total samples, MD5) are reset to "unknown".
+type flacMuxer struct {
+	w          io.Writer
+	streamInfo []byte
+}
+
+func (m *flacMuxer) Add(chunk []byte) error {
+	streamInfo, frames, err := parseFLAC(chunk)
+	if err != nil {
+		return err
+	}
+	if m.streamInfo == nil {
+		chunk = append([]byte(nil), chunk...)
+		streamInfo, _, _ = parseFLAC(chunk)
+		m.streamInfo = streamInfo
+		// Frame sizes (bytes 4-9), total samples (low 36 bits of 10-17) and MD5 (18-33).
+		for i := 4; i < 10; i++ {
+			streamInfo[i] = 0
+		}
+		streamInfo[13] &= 0xf0
+		for i := 14; i < 34; i++ {
+			streamInfo[i] = 0
+		}
+		_, err := m.w.Write(chunk)
+		return err
+	}
+	// Sample rate, channels and bits per sample share bytes 10-13.
+	if !bytes.Equal(m.streamInfo[10:13], streamInfo[10:13]) || m.streamInfo[13]&0xf0 != streamInfo[13]&0xf0 {
+		return errors.New("flac: chunks use different sample formats")
+	}
+	_, err = m.w.Write(frames)
+	return err
+}
+
+func (m *flacMuxer) Finish() error { return nil }
+
+// parseFLAC returns the STREAMINFO block (aliasing b) and the audio frames
+// that follow the metadata.
+func parseFLAC(b []byte) (streamInfo, frames []byte, err error) {
+	if len(b) < 4 || string(b[:4]) != "fLaC" {
+		return nil, nil, errors.New("flac: missing fLaC marker")
+	}
+	off := 4
+	for {
+		if off+4 > len(b) {
+			return nil, nil, errors.New("flac: truncated metadata")
+		}
+		last := b[off]&0x80 != 0
+		typ := b[off] & 0x7f
+		size := int(b[off+1])<<16 | int(b[off+2])<<8 | int(b[off+3])
+		body := off + 4
+		if body+size > len(b) {
+			return nil, nil, errors.New("flac: truncated metadata")
+		}
+		if typ == 0 && size >= 34 {
+			streamInfo = b[body : body+size]
+		}
+		off = body + size
+		if last {
+			break
+		}
+	}
+	if streamInfo == nil {
+		return nil, nil, errors.New("flac: missing STREAMINFO")
+	}
+	return streamInfo, b[off:], nil
+}
+
+// oggMuxer remuxes Ogg Opus chunks into one logical stream: later chunks lose
+// their OpusHead/OpusTags pages and every page is renumbered onto the first
+// chunk's serial number with continuous granule positions.
+type oggMuxer struct {
+	w       io.Writer
+	serial  uint32
+	seq     uint32
+	offset  int64
+	started bool
+	pending []byte
+}
+
+func (m *oggMuxer) Add(chunk []byte) error {
+	pages, err := splitOggPages(chunk)
+	if err != nil {
+		return err
+	}
+	if !m.started {
+		m.serial = binary.LittleEndian.Uint32(pages[0][14:18])
+	}
+	headerPackets := 0
+	var last int64
+	for _, page := range pages {
+		if headerPackets < 2 {
+			headerPackets += oggPacketEnds(page)
+			if m.started {
+				continue
+			}
+		}
+		out := append([]byte(nil), page...)
+		if m.started {
+			out[5] &^= 0x02 // only the very first page begins the stream
+		}
+		out[5] &^= 0x04 // end of stream is set on the final page in Finish
+		if g := int64(binary.LittleEndian.Uint64(out[6:14])); g != -1 {
+			last = g
+			binary.LittleEndian.PutUint64(out[6:14], uint64(m.offset+g))
+		}
+		binary.LittleEndian.PutUint32(out[14:18], m.serial)
+		binary.LittleEndian.PutUint32(out[18:22], m.seq)
+		m.seq++
+		if err := m.flushPending(); err != nil {
+			return err
+		}
+		m.pending = out
+	}
+	m.offset += last
+	m.started = true
+	return nil
+}
+
+func (m *oggMuxer) Finish() error {
+	if m.pending != nil {
+		m.pending[5] |= 0x04
+	}
+	return m.flushPending()
+}
+
+func (m *oggMuxer) flushPending() error {
+	if m.pending == nil {
+		return nil
+	}
+	page := m.pending
+	m.pending = nil
+	binary.LittleEndian.PutUint32(page[22:26], 0)
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	_, err := m.w.Write(page)
+	return err
+}
+
+// splitOggPages splits b into complete Ogg pages.
+func splitOggPages(b []byte) ([][]byte, error) {
+	var pages [][]byte
+	for off := 0; off < len(b); {
+		if len(b)-off < 27 || string(b[off:off+4]) != "OggS" {
+			return nil, errors.New("ogg: invalid page header")
+		}
+		nsegs := int(b[off+26])
+		if len(b)-off < 27+nsegs {
+			return nil, errors.New("ogg: truncated page")
+		}
+		size := 27 + nsegs
+		for _, lace := range b[off+27 : off+27+nsegs] {
+			size += int(lace)
+		}
+		if len(b)-off < size {
+			return nil, errors.New("ogg: truncated page")
+		}
+		pages = append(pages, b[off:off+size])
+		off += size
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("ogg: no pages")
+	}
+	return pages, nil
+}
+
+// oggPacketEnds counts the packets that finish on page.
+func oggPacketEnds(page []byte) int {
+	n := 0
+	for _, lace := range page[27 : 27+int(page[26])] {
+		if lace < 255 {
+			n++
+		}
+	}
+	return n
+}
+
+var oggCRCTable = func() [256]uint32 {
+	var t [256]uint32
+	for i := range t {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		t[i] = r
+	}
+	return t
+}()
+
+// oggCRC computes the Ogg page checksum (CRC-32, polynomial 0x04c11db7,
+// unreflected) over a page whose checksum field is zero.
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// memFile is an in-memory io.WriteSeeker.
+type memFile struct {
+	data []byte
+	pos  int64
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if end := f.pos + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	n := copy(f.data[f.pos:], p)
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	default:
+		return 0, errors.New("memFile: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("memFile: negative position")
+	}
+	f.pos = offset
+	return offset, nil
+}
@@ -0,0 +1,203 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func wavFile(data []byte, declaredSize uint32) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+len(data)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 1})         // PCM, mono
+	binary.Write(&b, binary.LittleEndian, []uint32{24000, 48000}) // rate, byte rate
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})        // block align, bits
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, declaredSize)
+	b.Write(data)
+	return b.Bytes()
+}
+
+func TestJoinAudioWAV(t *testing.T) {
+	a := wavFile([]byte{1, 2, 3, 4}, 4)
+	b := wavFile([]byte{5, 6, 7, 8, 9, 10}, 0xffffffff) // streamed header with unknown size
+	// Two files back to back, as an engine writing one WAV per line prints.
+	lines := append(wavFile([]byte{11, 12}, 2), wavFile([]byte{13, 14}, 2)...)
+
+	out, err := JoinAudio("wav", [][]byte{a, lines, b})
+	if err != nil {
+		t.Fatalf("JoinAudio error: %v", err)
+	}
+	if got := bytes.Count(out, []byte("RIFF")); got != 1 {
+		t.Fatalf("expected one RIFF header, got %d", got)
+	}
+	if size := binary.LittleEndian.Uint32(out[4:8]); int(size) != len(out)-8 {
+		t.Fatalf("RIFF size %d, want %d", size, len(out)-8)
+	}
+	fmtChunk, data, err := parseWAV(out)
+	if err != nil {
+		t.Fatalf("parseWAV error: %v", err)
+	}
+	if len(fmtChunk) != 16 || !bytes.Equal(data, []byte{1, 2, 3, 4, 11, 12, 13, 14, 5, 6, 7, 8, 9, 10}) {
+		t.Fatalf("unexpected fmt %v / data %v", fmtChunk, data)
+	}
+	if size := binary.LittleEndian.Uint32(out[40:44]); size != 14 {
+		t.Fatalf("data size %d, want 14", size)
+	}
+
+	stereo := wavFile([]byte{1, 2}, 2)
+	stereo[22] = 2
+	if _, err := JoinAudio("wav", [][]byte{append(wavFile([]byte{1, 2}, 2), stereo...)}); err == nil {
+		t.Fatal("back-to-back files with different sample formats should fail")
+	}
+}
+
+func mp3Frame(tag string, fill byte) []byte {
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz, mono: 417-byte frames.
+	frame := bytes.Repeat([]byte{fill}, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0xc4})
+	for i := 4; i < 4+17; i++ {
+		frame[i] = 0
+	}
+	if tag != "" {
+		copy(frame[4+17:], tag)
+	}
+	return frame
+}
+
+func TestJoinAudioMP3(t *testing.T) {
+	id3 := append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 5}, []byte("hello")...)
+	chunk := func(fill byte) []byte {
+		b := append([]byte(nil), id3...)
+		b = append(b, mp3Frame("Info", 0)...)
+		return append(b, mp3Frame("", fill)...)
+	}
+
+	out, err := JoinAudio("mp3", [][]byte{chunk(0x11), chunk(0x22)})
+	if err != nil {
+		t.Fatalf("JoinAudio error: %v", err)
+	}
+	if bytes.Contains(out, []byte("ID3")) || bytes.Contains(out, []byte("Info")) {
+		t.Fatalf("tags or VBR header frames left in output")
+	}
+	if len(out) != 2*417 || mp3FrameLen(out) != 417 || mp3FrameLen(out[417:]) != 417 {
+		t.Fatalf("expected two back-to-back audio frames, got %d bytes", len(out))
+	}
+}
+
+func flacFile(frames string) []byte {
+	info := make([]byte, 34)
+	binary.BigEndian.PutUint16(info[0:], 4096)
+	binary.BigEndian.PutUint16(info[2:], 4096)
+	// 24000 Hz, 1 channel, 16 bits, 1234 samples.
+	sr := uint64(24000)<<44 | uint64(0)<<41 | uint64(15)<<36 | 1234
+	binary.BigEndian.PutUint64(info[10:], sr)
+	copy(info[18:], bytes.Repeat([]byte{0xab}, 16))
+	b := []byte("fLaC")
+	b = append(b, 0x80, 0, 0, 34)
+	b = append(b, info...)
+	return append(b, frames...)
+}
+
+func TestJoinAudioFLAC(t *testing.T) {
+	out, err := JoinAudio("flac", [][]byte{flacFile("FRAME1"), flacFile("FRAME2")})
+	if err != nil {
+		t.Fatalf("JoinAudio error: %v", err)
+	}
+	info, frames, err := parseFLAC(out)
+	if err != nil {
+		t.Fatalf("parseFLAC error: %v", err)
+	}
+	if string(frames) != "FRAME1FRAME2" {
+		t.Fatalf("unexpected frames %q", frames)
+	}
+	packed := binary.BigEndian.Uint64(info[10:18])
+	if packed>>44 != 24000 || packed&(1<<36-1) != 0 {
+		t.Fatalf("sample rate kept and total samples cleared expected, got %x", packed)
+	}
+	if !bytes.Equal(info[18:34], make([]byte, 16)) {
+		t.Fatalf("expected MD5 cleared")
+	}
+}
+
+func oggPage(flags byte, granule int64, serial, seq uint32, packets ...[]byte) []byte {
+	var lacing, body []byte
+	for _, p := range packets {
+		n := len(p)
+		for n >= 255 {
+			lacing = append(lacing, 255)
+			n -= 255
+		}
+		lacing = append(lacing, byte(n))
+		body = append(body, p...)
+	}
+	page := []byte("OggS\x00")
+	page = append(page, flags)
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = binary.LittleEndian.AppendUint32(page, seq)
+	page = binary.LittleEndian.AppendUint32(page, 0)
+	page = append(page, byte(len(lacing)))
+	page = append(page, lacing...)
+	page = append(page, body...)
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	return page
+}
+
+func opusFile(serial uint32) []byte {
+	var b []byte
+	b = append(b, oggPage(0x02, 0, serial, 0, []byte("OpusHead........"))...)
+	b = append(b, oggPage(0, 0, serial, 1, []byte("OpusTags........"))...)
+	b = append(b, oggPage(0, 960, serial, 2, []byte("audio-1"))...)
+	b = append(b, oggPage(0x04, 1920, serial, 3, []byte("audio-2"))...)
+	return b
+}
+
+func TestJoinAudioOpus(t *testing.T) {
+	out, err := JoinAudio("opus", [][]byte{opusFile(7), opusFile(9)})
+	if err != nil {
+		t.Fatalf("JoinAudio error: %v", err)
+	}
+	pages, err := splitOggPages(out)
+	if err != nil {
+		t.Fatalf("splitOggPages error: %v", err)
+	}
+	if len(pages) != 6 {
+		t.Fatalf("expected 6 pages (headers once), got %d", len(pages))
+	}
+	wantGranules := []int64{0, 0, 960, 1920, 2880, 3840}
+	for i, page := range pages {
+		flags := page[5]
+		if (flags&0x02 != 0) != (i == 0) || (flags&0x04 != 0) != (i == len(pages)-1) {
+			t.Fatalf("page %d has flags %#x", i, flags)
+		}
+		if g := int64(binary.LittleEndian.Uint64(page[6:14])); g != wantGranules[i] {
+			t.Fatalf("page %d granule %d, want %d", i, g, wantGranules[i])
+		}
+		if s := binary.LittleEndian.Uint32(page[14:18]); s != 7 {
+			t.Fatalf("page %d serial %d, want 7", i, s)
+		}
+		if seq := binary.LittleEndian.Uint32(page[18:22]); seq != uint32(i) {
+			t.Fatalf("page %d sequence %d", i, seq)
+		}
+		crc := binary.LittleEndian.Uint32(page[22:26])
+		check := append([]byte(nil), page...)
+		binary.LittleEndian.PutUint32(check[22:26], 0)
+		if oggCRC(check) != crc {
+			t.Fatalf("page %d has a bad checksum", i)
+		}
+	}
+}
+
+func TestJoinAudioRawAndUnknown(t *testing.T) {
+	out, err := JoinAudio("aac", [][]byte{[]byte("AB"), []byte("CD")})
+	if err != nil || string(out) != "ABCD" {
+		t.Fatalf("JoinAudio(aac) = %q, %v", out, err)
+	}
+	if _, err := JoinAudio("ogg-vorbis", nil); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,278 @@
+package convert
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// URLMode selects how bare URLs in the text are spoken.
+type URLMode string
+
+const (
+	// URLElide replaces each URL with the word "link".
+	URLElide URLMode = "elide"
+	// URLSpeak reads the host and path, e.g. "example dot com slash docs".
+	URLSpeak URLMode = "speak"
+)
+
+// ParseURLMode validates a URL mode name; the empty string selects URLElide.
+func ParseURLMode(s string) (URLMode, error) {
+	switch mode := URLMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return URLElide, nil
+	case URLElide, URLSpeak:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown URL mode %q (want elide or speak)", s)
+	}
+}
+
+// localeWords holds the vocabulary a normalizer needs for one language.
+type localeWords struct {
+	link       string
+	dot        string
+	slash      string
+	version    string
+	point      string
+	about      string
+	to         string
+	from       string
+	and        string
+	percent    string
+	plusMinus  string
+	months     [12]string
+	magnitudes map[string]string
+	// currencies maps a symbol to its singular and plural unit names.
+	currencies map[string][2]string
+	minorUnit  [2]string
+	phrases    []phraseRule
+	dateFormat func(year, day int, month string) string
+}
+
+// localeTable lists the languages Normalize knows how to expand.
+var localeTable = map[string]*localeWords{
+	"en": {
+		link:      "link",
+		dot:       "dot",
+		slash:     "slash",
+		version:   "version",
+		point:     "point",
+		about:     "about",
+		to:        "to",
+		from:      "from",
+		and:       "and",
+		percent:   "percent",
+		plusMinus: "plus or minus",
+		months: [12]string{"January", "February", "March", "April", "May", "June",
+			"July", "August", "September", "October", "November", "December"},
+		magnitudes: map[string]string{"k": "thousand", "m": "million", "b": "billion", "bn": "billion"},
+		currencies: map[string][2]string{
+			"$": {"dollar", "dollars"},
+			"€": {"euro", "euros"},
+			"£": {"pound", "pounds"},
+			"¥": {"yen", "yen"},
+		},
+		minorUnit: [2]string{"cent", "cents"},
+		phrases: []phraseRule{
+			newPhrase("e.g.", "for example"),
+			newPhrase("i.e.", "that is"),
+			newPhrase("vs.", "versus"),
+			newPhrase("w/o", "without"),
+			newPhrase("w/", "with"),
+		},
+		dateFormat: func(year, day int, month string) string {
+			return fmt.Sprintf("%s %d, %d", month, day, year)
+		},
+	},
+}
+
+var (
+	urlRe       = regexp.MustCompile(`\b(?:https?://|www\.)[^\s<>()"']+`)
+	isoDateRe   = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	versionRe   = regexp.MustCompile(`\b[vV](\d+(?:\.\d+)*)\b`)
+	semverRe    = regexp.MustCompile(`\b\d+\.\d+\.\d+\b`)
+	currencyRe  = regexp.MustCompile(`([$€£¥])\s?(\d[\d,]*)(?:\.(\d{1,2}))?(?:\s?([kKmMbB]n?)\b)?`)
+	approxRe    = regexp.MustCompile(`~\s?(\d)`)
+	approxKRe   = regexp.MustCompile(`~\s?(\d+(?:\.\d+)?)K\b`)
+	magnitudeRe = regexp.MustCompile(`\b(\d+(?:\.\d+)?)(k|M|B|bn)\b`)
+	percentRe   = regexp.MustCompile(`(\d)\s?%`)
+	arrowToRe   = regexp.MustCompile(`\s*(?:->|=>|→)\s*`)
+	arrowFromRe = regexp.MustCompile(`\s*(?:<-|←)\s*`)
+	ampRe       = regexp.MustCompile(`\s*&\s*`)
+	plusMinusRe = regexp.MustCompile(`\+/-|±`)
+	spacesRe    = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// Normalize expands numbers, dates, units, URLs and symbols that TTS engines
+// read badly into speakable words for cfg.Locale (English by default). URLs
+// are spoken or elided according to cfg.URLs. Locales without a word table
+// only have their URLs handled.
+func Normalize(text string, cfg Config) string {
+	w := localeFor(cfg.Locale)
+	mode, err := ParseURLMode(string(cfg.URLs))
+	if err != nil {
+		mode = URLElide
+	}
+	words := w
+	if words == nil {
+		words = localeTable["en"]
+	}
+	text = urlRe.ReplaceAllStringFunc(text, func(raw string) string {
+		trimmed := strings.TrimRight(raw, ".,;:!?")
+		return speakURL(trimmed, mode, words) + raw[len(trimmed):]
+	})
+	if w == nil {
+		return text
+	}
+
+	text = isoDateRe.ReplaceAllStringFunc(text, func(s string) string {
+		m := isoDateRe.FindStringSubmatch(s)
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return s
+		}
+		return w.dateFormat(year, day, w.months[month-1])
+	})
+	text = versionRe.ReplaceAllStringFunc(text, func(s string) string {
+		return w.version + " " + strings.ReplaceAll(s[1:], ".", " "+w.point+" ")
+	})
+	text = replaceSemver(text, w.point)
+	text = currencyRe.ReplaceAllStringFunc(text, func(s string) string {
+		return w.currency(currencyRe.FindStringSubmatch(s))
+	})
+	// An upper-case K is a magnitude only in amounts and after "~", so that
+	// "4K video" is not read as "4 thousand video".
+	text = approxKRe.ReplaceAllString(text, "~${1}k")
+	text = approxRe.ReplaceAllString(text, w.about+" $1")
+	text = magnitudeRe.ReplaceAllStringFunc(text, func(s string) string {
+		m := magnitudeRe.FindStringSubmatch(s)
+		return m[1] + " " + w.magnitudes[strings.ToLower(m[2])]
+	})
+	text = percentRe.ReplaceAllString(text, "$1 "+w.percent)
+	text = plusMinusRe.ReplaceAllString(text, " "+w.plusMinus+" ")
+	text = arrowToRe.ReplaceAllString(text, " "+w.to+" ")
+	text = arrowFromRe.ReplaceAllString(text, " "+w.from+" ")
+	text = ampRe.ReplaceAllString(text, " "+w.and+" ")
+	for _, p := range w.phrases {
+		text = p.re.ReplaceAllString(text, "${1}"+p.spoken)
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spacesRe.ReplaceAllString(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceSemver speaks dotted version triples such as 2.3.1, leaving longer
+// dotted numbers such as IPv4 addresses untouched.
+func replaceSemver(text, point string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range semverRe.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if dottedDigit(text[end:]) || (start > 0 && text[start-1] == '.' && start > 1 && isDigit(text[start-2])) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(strings.ReplaceAll(text[start:end], ".", " "+point+" "))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// dottedDigit reports whether s starts with a period followed by a digit.
+func dottedDigit(s string) bool {
+	return len(s) > 1 && s[0] == '.' && isDigit(s[1])
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// localeFor returns the word table for a locale such as "en" or "en-GB", or
+// nil when the language is not supported. The empty locale means English.
+func localeFor(locale string) *localeWords {
+	lang := strings.ToLower(strings.TrimSpace(locale))
+	if lang == "" {
+		lang = "en"
+	}
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return localeTable[lang]
+}
+
+// speakURL renders a bare URL as speech or as a short placeholder.
+func speakURL(raw string, mode URLMode, w *localeWords) string {
+	if mode != URLSpeak {
+		return w.link
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("http://" + raw)
+		if err != nil {
+			return w.link
+		}
+	}
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	parts := []string{strings.ReplaceAll(host, ".", " "+w.dot+" ")}
+	for _, seg := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if seg != "" {
+			parts = append(parts, strings.ReplaceAll(seg, ".", " "+w.dot+" "))
+		}
+	}
+	return strings.Join(parts, " "+w.slash+" ")
+}
+
+// currency renders a currencyRe match such as "$1.50" or "€5k".
+func (w *localeWords) currency(m []string) string {
+	units := w.currencies[m[1]]
+	whole := strings.ReplaceAll(m[2], ",", "")
+	if mag := strings.ToLower(m[4]); mag != "" {
+		amount := whole
+		if m[3] != "" {
+			amount += "." + m[3]
+		}
+		return fmt.Sprintf("%s %s %s", amount, w.magnitudes[mag], units[1])
+	}
+	n, _ := strconv.Atoi(whole)
+	out := fmt.Sprintf("%s %s", whole, pick(n, units))
+	if m[3] != "" {
+		cents, _ := strconv.Atoi(m[3])
+		if len(m[3]) == 1 {
+			cents *= 10
+		}
+		if cents > 0 {
+			out += fmt.Sprintf(" %s %d %s", w.and, cents, pick(cents, w.minorUnit))
+		}
+	}
+	return out
+}
+
+// pick returns the singular or plural form for n.
+func pick(n int, forms [2]string) string {
+	if n == 1 {
+		return forms[0]
+	}
+	return forms[1]
+}
+
+// phraseRule replaces an abbreviation wherever it starts a word.
+type phraseRule struct {
+	re     *regexp.Regexp
+	spoken string
+}
+
+func newPhrase(phrase, spoken string) phraseRule {
+	return phraseRule{
+		re:     regexp.MustCompile(`(^|[^\pL\pN])` + regexp.QuoteMeta(phrase)),
+		spoken: strings.ReplaceAll(spoken, "$", "$$"),
+	}
+}
@@ -0,0 +1,43 @@
+package convert
+
+import "testing"
+
+func TestNormalizeEnglish(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"Requires Go v1.24.0 or newer.", "Requires Go version 1 point 24 point 0 or newer."},
+		{"Release 2.3.1 shipped on 2024-03-05.", "Release 2 point 3 point 1 shipped on March 5, 2024."},
+		{"Chunks are ~6k characters.", "Chunks are about 6 thousand characters."},
+		{"Input -> output & back", "Input to output and back"},
+		{"It costs $1.50, or $3 total.", "It costs 1 dollar and 50 cents, or 3 dollars total."},
+		{"A €2M budget", "A 2 million euros budget"},
+		{"Uptime is 99.9% ± 0.1", "Uptime is 99.9 percent plus or minus 0.1"},
+		{"Tools, e.g. make, w/o tests", "Tools, for example make, without tests"},
+		{"Read https://example.com/docs/intro.html.", "Read link."},
+		{"Ping 192.168.0.1 first.", "Ping 192.168.0.1 first."},
+		{"Record 4K video, ~6K rows, $2K or 3k words.", "Record 4K video, about 6 thousand rows, 2 thousand dollars or 3 thousand words."},
+	}
+	for _, tc := range cases {
+		if got := Normalize(tc.in, Config{}); got != tc.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeSpeaksURLs(t *testing.T) {
+	got := Normalize("See https://www.example.com/docs/intro.html, then www.go.dev.", Config{URLs: URLSpeak})
+	want := "See example dot com slash docs slash intro dot html, then go dot dev."
+	if got != want {
+		t.Fatalf("Normalize() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeUnknownLocaleOnlyHandlesURLs(t *testing.T) {
+	in := "Preis: $5 & https://example.de"
+	want := "Preis: $5 & link"
+	if got := Normalize(in, Config{Locale: "de-DE"}); got != want {
+		t.Fatalf("Normalize() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// openAIBaseURL is the root of OpenAI's API.
+	openAIBaseURL = "https://api.openai.com/v1"
+	// openAIEndpoint is OpenAI's speech endpoint.
+	openAIEndpoint = openAIBaseURL + "/audio/speech"
+	// azureAPIVersion is the Azure OpenAI api-version used when none is set.
+	azureAPIVersion = "2025-03-01-preview"
+)
+
+// openAIVoices are the voices of OpenAI's speech models.
+var openAIVoices = []string{"alloy", "ash", "ballad", "coral", "echo", "fable", "nova", "onyx", "sage", "shimmer", "verse"}
+
+// openAIClient speaks the OpenAI /audio/speech protocol, either to OpenAI
+// itself or to a compatible server.
+type openAIClient struct {
+	httpClient *http.Client
+	retry      RetryPolicy
+	endpoint   string
+	// requireKey rejects requests without an API key; compatible servers
+	// often run without one.
+	requireKey bool
+	// voices lists the accepted voices; empty accepts any name.
+	voices []string
+	// headers are sent with every request, after the defaults.
+	headers map[string]string
+	// keyHeader carries the API key verbatim, as Azure's "api-key" does;
+	// empty sends it as an Authorization bearer token.
+	keyHeader string
+}
+
+// newOpenAIClient builds a client for the speech endpoint described by pc,
+// rooted at pc.BaseURL or base. A deployment selects Azure-style routing:
+// the path names the deployment, api-version defaults to azureAPIVersion
+// and the key travels in an "api-key" header.
+func newOpenAIClient(pc ProviderConfig, base string) (*openAIClient, error) {
+	if pc.BaseURL != "" {
+		base = pc.BaseURL
+	}
+	path := pc.Path
+	if path == "" {
+		path = "/audio/speech"
+		if pc.Deployment != "" {
+			path = "/openai/deployments/" + url.PathEscape(pc.Deployment) + "/audio/speech"
+		}
+	}
+	endpoint, err := url.Parse(strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid API base URL %q", base)
+	}
+	version := pc.APIVersion
+	if version == "" && pc.Deployment != "" {
+		version = azureAPIVersion
+	}
+	if version != "" {
+		q := endpoint.Query()
+		q.Set("api-version", version)
+		endpoint.RawQuery = q.Encode()
+	}
+	c := &openAIClient{httpClient: pc.httpClient(), endpoint: endpoint.String(), headers: pc.Headers, retry: pc.Retry}
+	if pc.Deployment != "" {
+		c.keyHeader = "api-key"
+	}
+	return c, nil
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 90 * time.Second}
+
+// Voices lists the voices the endpoint is known to accept.
+func (c *openAIClient) Voices() []string {
+	return c.voices
+}
+
+// Formats lists the speech endpoint's response formats.
+func (c *openAIClient) Formats() []string {
+	return []string{"mp3", "opus", "aac", "flac", "wav", "pcm"}
+}
+
+// InputLimit reports the speech endpoint's 4096-character input cap.
+func (c *openAIClient) InputLimit() InputLimit {
+	return InputLimit{Max: 4096, Unit: LimitRunes}
+}
+
+func (c *openAIClient) Synthesize(ctx context.Context, cfg Config, chunk string) ([]byte, error) {
+	if cfg.APIKey == "" && c.requireKey {
+		return nil, errors.New("API key is missing")
+	}
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	endpoint := c.endpoint
+	if endpoint == "" {
+		endpoint = openAIEndpoint
+	}
+
+	payload := map[string]any{
+		"model":           cfg.Model,
+		"input":           chunk,
+		"voice":           cfg.Voice,
+		"response_format": cfg.ResponseFormat,
+	}
+	if cfg.Speed > 0 && cfg.Speed != 1.0 {
+		payload["speed"] = cfg.Speed
+	}
+	if strings.TrimSpace(cfg.Instructions) != "" {
+		payload["instructions"] = cfg.Instructions
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	switch {
+	case cfg.APIKey == "":
+	case c.keyHeader != "":
+		headers[c.keyHeader] = cfg.APIKey
+	default:
+		headers["Authorization"] = "Bearer " + cfg.APIKey
+	}
+	maps.Copy(headers, c.headers)
+	return postWithRetry(ctx, httpClient, c.retry, cfg.RateLimit, utf8.RuneCountInString(chunk), endpoint, headers, body)
+}
+
+type apiError struct {
+	code      int
+	status    string
+	message   string
+	retryable bool
+	// retryAfter is the wait the server asked for before retrying; zero
+	// when it named none.
+	retryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.status, e.message)
+}
+
+func doTTSRequest(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		err := &apiError{
+			code:      resp.StatusCode,
+			status:    resp.Status,
+			message:   strings.TrimSpace(string(snippet)),
+			retryable: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			err.retryAfter = retryAfter(resp.Header, time.Now())
+		}
+		return err
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,140 @@
+package convert
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Orphan is a file in the output tree whose markdown source no longer exists:
+// audio in any supported format, its manifest, or a leftover partial file.
+type Orphan struct {
+	Path    string
+	RelPath string
+}
+
+// FindOrphans compares the output tree with jobs and returns the files whose
+// source is gone. Audio kept for a source that still exists is never an
+// orphan, whatever its format. The checkpoint work dir and skip (typically an
+// archive folder) are not scanned.
+func FindOrphans(outDir string, jobs []FileJob, skip string) ([]Orphan, error) {
+	outDir = filepath.Clean(outDir)
+	skip, err := skipPath(outDir, skip)
+	if err != nil {
+		return nil, err
+	}
+	stems := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		stems[audioStem(job.DestPath)] = true
+	}
+
+	var out []Orphan
+	err = filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != outDir && (d.Name() == WorkDirName || (skip != "" && path == skip)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		switch {
+		case strings.HasPrefix(name, ".") && strings.HasSuffix(name, partialSuffix):
+			// A partial for a live source belongs to a run in progress.
+			stem := strings.TrimSuffix(strings.TrimPrefix(name, "."), partialSuffix)
+			if stems[filepath.Join(filepath.Dir(path), audioStem(audioStem(stem)))] {
+				return nil
+			}
+		case isAudioFile(strings.TrimSuffix(name, ManifestSuffix)):
+			if stems[audioStem(strings.TrimSuffix(path, ManifestSuffix))] {
+				return nil
+			}
+		default:
+			return nil
+		}
+		rel, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		out = append(out, Orphan{Path: path, RelPath: rel})
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RelPath < out[j].RelPath })
+	return out, err
+}
+
+// skipPath returns skip as a path under outDir in the form the walk of outDir
+// produces, whether either is relative or absolute, or "" when skip is empty
+// or outside outDir.
+func skipPath(outDir, skip string) (string, error) {
+	if skip == "" {
+		return "", nil
+	}
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return "", err
+	}
+	absSkip, err := filepath.Abs(skip)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absOut, absSkip)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", nil
+	}
+	return filepath.Join(outDir, rel), nil
+}
+
+// RemoveOrphans deletes orphans, or moves them under archiveDir keeping their
+// relative paths when archiveDir is set. Directories in outDir left empty are
+// removed.
+func RemoveOrphans(outDir string, orphans []Orphan, archiveDir string) error {
+	for _, o := range orphans {
+		if archiveDir == "" {
+			if err := os.Remove(o.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		} else {
+			dest := filepath.Join(archiveDir, o.RelPath)
+			if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+				return err
+			}
+			if err := os.Rename(o.Path, dest); err != nil {
+				return err
+			}
+		}
+		removeEmptyDirs(filepath.Dir(o.Path), outDir)
+	}
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty, stopping
+// at root.
+func removeEmptyDirs(dir, root string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// isAudioFile reports whether name has the extension of a supported format.
+func isAudioFile(name string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	return ext != "" && slices.Contains(muxFormats, ext)
+}
+
+// audioStem strips the format extension from an audio path.
+func audioStem(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
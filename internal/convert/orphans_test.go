@@ -0,0 +1,93 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindAndRemoveOrphans(t *testing.T) {
+	root := t.TempDir()
+	in := filepath.Join(root, "in")
+	out := filepath.Join(root, "out")
+	files := []string{
+		filepath.Join(in, "keep.md"),
+		filepath.Join(out, "keep.aac"),
+		filepath.Join(out, "keep.mp3"),
+		filepath.Join(out, "gone", "old.aac"),
+		filepath.Join(out, "gone", "old.aac"+ManifestSuffix),
+		filepath.Join(out, ".removed.aac.42"+partialSuffix),
+		filepath.Join(out, ".keep.aac.7"+partialSuffix),
+		filepath.Join(out, "readme.txt"),
+		filepath.Join(out, WorkDirName, "abc", "chunk"),
+		filepath.Join(out, "archive", "older", "ancient.aac"),
+	}
+	for _, path := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := CollectMarkdownFiles(in, out, "*.md", "aac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(out, "archive")
+	orphans, err := FindOrphans(out, jobs, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range orphans {
+		got = append(got, o.RelPath)
+	}
+	want := []string{".removed.aac.42" + partialSuffix, filepath.Join("gone", "old.aac"), filepath.Join("gone", "old.aac"+ManifestSuffix)}
+	if len(got) != len(want) {
+		t.Fatalf("orphans = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("orphans = %v, want %v", got, want)
+		}
+	}
+
+	if err := RemoveOrphans(out, orphans, archive); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(archive, "gone", "old.aac")); err != nil {
+		t.Fatalf("orphan not archived: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "gone")); !os.IsNotExist(err) {
+		t.Fatalf("empty directory should be removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "keep.mp3")); err != nil {
+		t.Fatalf("audio for a live source was removed: %v", err)
+	}
+}
+
+func TestFindOrphansSkipsArchiveAcrossPathForms(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+	ancient := filepath.Join(root, "out", "archive", "ancient.aac")
+	if err := os.MkdirAll(filepath.Dir(ancient), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ancient, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ out, archive string }{
+		{"out", filepath.Join(root, "out", "archive")},
+		{filepath.Join(root, "out"), filepath.Join("out", "archive")},
+	} {
+		orphans, err := FindOrphans(tt.out, nil, tt.archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orphans) != 0 {
+			t.Errorf("FindOrphans(%q, archive %q) = %v, want the archive skipped", tt.out, tt.archive, orphans)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// partialSuffix marks audio that is still being written. Such files live next
+// to their destination and are renamed into place only once complete.
+const partialSuffix = ".partial"
+
+// writeFileAtomic writes data to dir/name via a partial temp file in dir, so
+// that a crash never leaves a truncated file behind.
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, name+".*"+partialSuffix)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// partialFile is a temp file that becomes the destination audio on commit.
+type partialFile struct {
+	*os.File
+	done bool
+}
+
+// createPartial creates a hidden temp file in dest's directory, so that the
+// final rename stays on one filesystem. Leftovers from a crashed run for the
+// same destination are removed first.
+func createPartial(dest string) (*partialFile, error) {
+	pattern := "." + filepath.Base(dest) + ".*" + partialSuffix
+	if stale, _ := filepath.Glob(filepath.Join(filepath.Dir(dest), pattern)); len(stale) > 0 {
+		for _, name := range stale {
+			os.Remove(name)
+		}
+	}
+	f, err := os.CreateTemp(filepath.Dir(dest), pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &partialFile{File: f}, nil
+}
+
+// commit flushes the file to disk and renames it to dest.
+func (p *partialFile) commit(dest string) error {
+	if err := p.Sync(); err != nil {
+		return err
+	}
+	if err := p.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(p.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(p.Name(), dest); err != nil {
+		return err
+	}
+	p.done = true
+	return nil
+}
+
+// discard removes the temp file unless it was committed. It is safe to call
+// after commit, which makes it suitable for defer.
+func (p *partialFile) discard() {
+	if p.done {
+		return
+	}
+	p.Close()
+	os.Remove(p.Name())
+}
@@ -0,0 +1,67 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessFileLeavesNoPartialOnFailure(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	if err := os.WriteFile(src, []byte("# One\n\nFirst.\n\n# Two\n\nSecond."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(root, "out")
+	dest := filepath.Join(outDir, "file.aac")
+
+	mock := &mockTTSClient{resp: []byte("AUDIO"), err: errors.New("boom"), failAt: 2}
+
+	cfg := Config{ResponseFormat: "aac", SectionLevel: 1, ChunkSize: 22}
+	res := newTestConverter(t, cfg, mock).ProcessFile(context.Background(), FileJob{AbsPath: src, DestPath: dest}, nil)
+	if res.Status != JobFailed {
+		t.Fatalf("status = %v, want failed", res.Status)
+	}
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("output dir should be empty, found %s", entries[0].Name())
+	}
+}
+
+func TestProcessFileReplacesStalePartial(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "file.md")
+	if err := os.WriteFile(src, []byte("Hello."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(root, "out")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(outDir, ".file.aac.123"+partialSuffix)
+	if err := os.WriteFile(stale, []byte("trunc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(outDir, "file.aac")
+
+	res := newTestConverter(t, Config{ResponseFormat: "aac"}, &mockTTSClient{resp: []byte("AUDIO")}).ProcessFile(context.Background(), FileJob{AbsPath: src, DestPath: dest}, nil)
+	if res.Status != JobDone {
+		t.Fatalf("status = %v (%v), want done", res.Status, res.Err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil || string(got) != "AUDIO" {
+		t.Fatalf("dest = %q, %v", got, err)
+	}
+	info, err := os.Stat(dest)
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("dest mode = %v, %v", info.Mode(), err)
+	}
+	if partials, _ := filepath.Glob(filepath.Join(outDir, "*"+partialSuffix)); len(partials) != 0 {
+		t.Fatalf("partial files left behind: %v", partials)
+	}
+}
@@ -0,0 +1,129 @@
+package convert
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SpeechCharsPerSecond approximates how many characters a TTS voice speaks
+// per second at speed 1.0 (about 150 words a minute).
+const SpeechCharsPerSecond = 15.0
+
+// ModelPrice is a model's list price in US dollars per million input
+// characters.
+type ModelPrice struct {
+	Model           string
+	PerMillionChars float64
+}
+
+// ModelPrices lists the speech models with known prices. Names match by
+// prefix, so "tts-1-hd-1106" is priced as "tts-1-hd".
+var ModelPrices = []ModelPrice{
+	{Model: "tts-1", PerMillionChars: 15},
+	{Model: "tts-1-hd", PerMillionChars: 30},
+	{Model: "gpt-4o-mini-tts", PerMillionChars: 15},
+}
+
+// PriceFor returns the price for model, matching the longest known prefix.
+func PriceFor(model string) (ModelPrice, bool) {
+	var best ModelPrice
+	found := false
+	for _, p := range ModelPrices {
+		if strings.HasPrefix(model, p.Model) && len(p.Model) > len(best.Model) {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// Cost returns the estimated price of synthesizing chars with p.
+func (p ModelPrice) Cost(chars int) float64 {
+	return float64(chars) * p.PerMillionChars / 1e6
+}
+
+// FilePlan is the dry-run estimate for one job.
+type FilePlan struct {
+	Job FileJob
+	// Skip is JobSkipped or JobEmpty when the file would not be synthesized,
+	// with Reason saying why; JobFailed when the source could not be read.
+	Skip   JobOutcome
+	Reason string
+	Err    error
+	Chunks int
+	// Chars counts the characters of every chunk.
+	Chars int
+	// Cached counts chunks already in Config.Cache; BilledChars counts the
+	// characters of the rest, which would be sent to the provider.
+	Cached      int
+	BilledChars int
+	Duration    time.Duration
+}
+
+// Plan is the dry-run estimate for a whole run.
+type Plan struct {
+	Files       []FilePlan
+	Convert     int
+	Skipped     int
+	Failed      int
+	Chunks      int
+	Chars       int
+	BilledChars int
+	Duration    time.Duration
+}
+
+// PlanFile estimates job without calling the TTS client.
+func (c *Converter) PlanFile(job FileJob) FilePlan {
+	fp := FilePlan{Job: job}
+	plan, err := c.planJob(job)
+	if err != nil {
+		fp.Skip, fp.Reason, fp.Err = JobFailed, err.Error(), err
+		return fp
+	}
+	if plan.skip != "" {
+		fp.Skip, fp.Reason = plan.skip, plan.reason
+		return fp
+	}
+	fp.Chunks = len(plan.chunks)
+	for _, chunk := range plan.chunks {
+		n := utf8.RuneCountInString(chunk.Text)
+		fp.Chars += n
+		if c.cfg.Cache.has(chunkKey(plan.cfg, chunk.Text)) {
+			fp.Cached++
+			continue
+		}
+		fp.BilledChars += n
+	}
+	fp.Duration = speechDuration(fp.Chars, plan.cfg.Speed)
+	return fp
+}
+
+// PlanJobs estimates every job; see PlanFile.
+func (c *Converter) PlanJobs(jobs []FileJob) Plan {
+	var p Plan
+	for _, job := range jobs {
+		fp := c.PlanFile(job)
+		p.Files = append(p.Files, fp)
+		switch fp.Skip {
+		case "":
+			p.Convert++
+		case JobFailed:
+			p.Failed++
+		default:
+			p.Skipped++
+		}
+		p.Chunks += fp.Chunks
+		p.Chars += fp.Chars
+		p.BilledChars += fp.BilledChars
+		p.Duration += fp.Duration
+	}
+	return p
+}
+
+// speechDuration estimates how long chars take to speak at speed.
+func speechDuration(chars int, speed float64) time.Duration {
+	if speed <= 0 {
+		speed = 1
+	}
+	return time.Duration(float64(chars) / (SpeechCharsPerSecond * speed) * float64(time.Second))
+}
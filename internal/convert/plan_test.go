@@ -0,0 +1,70 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlanJobsDoesNotSynthesize(t *testing.T) {
+	root := t.TempDir()
+	in := filepath.Join(root, "in")
+	out := filepath.Join(root, "out")
+	files := map[string]string{
+		"new.md":     "# One\n\nFirst part.\n\n# Two\n\nSecond part.",
+		"done.md":    "Already voiced.",
+		"skipped.md": "---\nskip: true\n---\nNot this.",
+	}
+	for name, body := range files {
+		if err := os.MkdirAll(in, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(in, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "done.aac"), []byte("A"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &mockTTSClient{}
+
+	jobs, err := CollectMarkdownFiles(in, out, "*.md", "aac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := newTestConverter(t, Config{ResponseFormat: "aac", SectionLevel: 1, ChunkSize: 22, Speed: 1}, mock).PlanJobs(jobs)
+	if mock.calls != 0 {
+		t.Fatalf("dry run called Synthesize %d times", mock.calls)
+	}
+	if plan.Convert != 1 || plan.Skipped != 2 || plan.Chunks != 2 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	want := len("One\n\nFirst part.") + len("Two\n\nSecond part.")
+	if plan.Chars != want || plan.BilledChars != want {
+		t.Fatalf("chars = %d billed %d, want %d", plan.Chars, plan.BilledChars, want)
+	}
+	if plan.Duration <= 0 || plan.Duration > time.Minute {
+		t.Fatalf("duration = %v", plan.Duration)
+	}
+	if _, err := os.Stat(filepath.Join(out, "new.aac")); !os.IsNotExist(err) {
+		t.Fatal("dry run wrote audio")
+	}
+}
+
+func TestPriceForMatchesLongestPrefix(t *testing.T) {
+	p, ok := PriceFor("tts-1-hd-1106")
+	if !ok || p.Model != "tts-1-hd" {
+		t.Fatalf("PriceFor(tts-1-hd-1106) = %+v, %v", p, ok)
+	}
+	if got := p.Cost(1_000_000); got != 30 {
+		t.Fatalf("Cost = %v, want 30", got)
+	}
+	if _, ok := PriceFor("unknown"); ok {
+		t.Fatal("unknown model should have no price")
+	}
+}
@@ -0,0 +1,58 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigName is the file looked up in the input root for settings a
+// team shares across runs.
+const ProjectConfigName = ".markloud.yaml"
+
+// ProjectConfig holds settings read from ProjectConfigName.
+type ProjectConfig struct {
+	// Lexicon is a lexicon file path, relative to the input root unless absolute.
+	Lexicon string `yaml:"lexicon"`
+	// Pronunciations are inline lexicon entries, applied after the Lexicon file.
+	Pronunciations []LexiconEntry `yaml:"pronunciations"`
+	// HeadingCues maps heading levels to spoken cues; see Config.HeadingCues.
+	HeadingCues map[int]string `yaml:"heading_cues"`
+}
+
+// Apply fills settings in cfg that the command line left unset.
+func (pc ProjectConfig) Apply(cfg Config) Config {
+	if len(pc.HeadingCues) > 0 {
+		cues := make(map[int]string, len(pc.HeadingCues)+len(cfg.HeadingCues))
+		for level, cue := range pc.HeadingCues {
+			cues[level] = cue
+		}
+		for level, cue := range cfg.HeadingCues {
+			cues[level] = cue
+		}
+		cfg.HeadingCues = cues
+	}
+	return cfg
+}
+
+// LoadProjectConfig reads ProjectConfigName from root. A missing file yields
+// a zero ProjectConfig.
+func LoadProjectConfig(root string) (ProjectConfig, error) {
+	var pc ProjectConfig
+	path := filepath.Join(root, ProjectConfigName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pc, nil
+	}
+	if err != nil {
+		return pc, err
+	}
+	if err := yaml.Unmarshal(data, &pc); err != nil {
+		return ProjectConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return pc, nil
+}
@@ -0,0 +1,233 @@
+package convert
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// VoiceLister is implemented by TTS clients that declare the voices they
+// know of. The list is advisory: other voice names are passed through and the
+// provider's API has the final word. An empty list means no voices are known.
+type VoiceLister interface {
+	Voices() []string
+}
+
+// ProviderConfig carries the settings a provider needs to build its client.
+type ProviderConfig struct {
+	// BaseURL overrides the provider's API root, e.g. for a proxy or a
+	// self-hosted OpenAI-compatible server. When empty, the provider's
+	// BaseURLEnv is consulted.
+	BaseURL string
+	// Path overrides the speech endpoint's path below BaseURL, e.g.
+	// "/v1/audio/speech" for a gateway rooted at the host.
+	Path string
+	// Headers are extra HTTP headers sent with every request.
+	Headers map[string]string
+	// APIVersion is sent as the api-version query parameter, as Azure
+	// OpenAI requires.
+	APIVersion string
+	// Deployment is an Azure OpenAI deployment name; it selects Azure-style
+	// routing and authentication.
+	Deployment string
+	// Command is the command line of the command provider; see
+	// NewCommandClient. For the piper, espeak-ng and festival providers it is
+	// the path of the engine's executable, when not on PATH.
+	Command string
+	// CommandFormat is the audio format the command writes; empty means wav.
+	CommandFormat string
+	// Retry says how HTTP providers retry failed requests.
+	Retry RetryPolicy
+	// HTTPClient sends the requests of HTTP providers; nil means a shared
+	// client with a 90-second timeout.
+	HTTPClient *http.Client
+}
+
+func (pc ProviderConfig) httpClient() *http.Client {
+	if pc.HTTPClient != nil {
+		return pc.HTTPClient
+	}
+	return defaultHTTPClient
+}
+
+// ProviderInfo describes a registered TTS provider.
+type ProviderInfo struct {
+	Name        string
+	Description string
+	// KeyEnv names the environment variable holding the API key; empty when
+	// the provider needs none.
+	KeyEnv string
+	// KeyOptional reports that requests go out without a key when KeyEnv is
+	// unset, for servers that may or may not check one.
+	KeyOptional bool
+	// BaseURLEnv names the environment variable that may hold the API root.
+	BaseURLEnv   string
+	DefaultModel string
+	DefaultVoice string
+	// DefaultFormat is used when the run's format is not one the provider
+	// produces.
+	DefaultFormat string
+	// Local reports that the provider synthesizes on this machine, without
+	// network access or API charges.
+	Local bool
+}
+
+// ProviderFactory builds a provider's client.
+type ProviderFactory func(pc ProviderConfig) (TTSClient, error)
+
+type registeredProvider struct {
+	info    ProviderInfo
+	factory ProviderFactory
+}
+
+var providers = map[string]registeredProvider{}
+
+// RegisterProvider adds a provider under info.Name, replacing any provider
+// of that name.
+func RegisterProvider(info ProviderInfo, factory ProviderFactory) {
+	providers[info.Name] = registeredProvider{info: info, factory: factory}
+}
+
+// Providers lists the registered providers by name, the default first.
+func Providers() []ProviderInfo {
+	out := make([]ProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		out = append(out, p.info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if (out[i].Name == DefaultProvider) != (out[j].Name == DefaultProvider) {
+			return out[i].Name == DefaultProvider
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// LookupProvider returns the provider registered as name; the empty name
+// selects DefaultProvider.
+func LookupProvider(name string) (ProviderInfo, bool) {
+	p, ok := providers[providerName(name)]
+	return p.info, ok
+}
+
+// NewTTSClient builds the client of the provider registered as name.
+func NewTTSClient(name string, pc ProviderConfig) (TTSClient, error) {
+	p, ok := providers[providerName(name)]
+	if !ok {
+		names := make([]string, 0, len(providers))
+		for _, info := range Providers() {
+			names = append(names, info.Name)
+		}
+		return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	if pc.BaseURL == "" && p.info.BaseURLEnv != "" {
+		pc.BaseURL = strings.TrimSpace(os.Getenv(p.info.BaseURLEnv))
+	}
+	return p.factory(pc)
+}
+
+// ParseHeader parses an HTTP header given as "Name: value".
+func ParseHeader(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (want \"Name: value\")", s)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// VoicesOf returns the voices client declares, or nil when it accepts any.
+func VoicesOf(client TTSClient) []string {
+	if l, ok := client.(VoiceLister); ok {
+		return l.Voices()
+	}
+	return nil
+}
+
+func providerName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DefaultProvider
+	}
+	return name
+}
+
+func init() {
+	RegisterProvider(ProviderInfo{
+		Name:          DefaultProvider,
+		Description:   "OpenAI text-to-speech",
+		KeyEnv:        "OPENAI_API_KEY",
+		BaseURLEnv:    "OPENAI_BASE_URL",
+		DefaultModel:  "tts-1-hd-1106",
+		DefaultVoice:  "alloy",
+		DefaultFormat: DefaultResponseFormat,
+	}, func(pc ProviderConfig) (TTSClient, error) {
+		c, err := newOpenAIClient(pc, openAIBaseURL)
+		if err != nil {
+			return nil, err
+		}
+		c.requireKey, c.voices = true, openAIVoices
+		return c, nil
+	})
+
+	RegisterProvider(ProviderInfo{
+		Name:          "openai-compatible",
+		Description:   "Any server speaking OpenAI's /audio/speech protocol",
+		KeyEnv:        "OPENAI_API_KEY",
+		KeyOptional:   true,
+		DefaultModel:  "tts-1",
+		DefaultVoice:  "alloy",
+		DefaultFormat: "mp3",
+	}, func(pc ProviderConfig) (TTSClient, error) {
+		if pc.BaseURL == "" {
+			return nil, fmt.Errorf("provider openai-compatible needs a base URL, e.g. http://localhost:8000/v1")
+		}
+		return newOpenAIClient(pc, "")
+	})
+
+	RegisterProvider(ProviderInfo{
+		Name:          "azure-openai",
+		Description:   "Azure OpenAI text-to-speech deployment",
+		KeyEnv:        "AZURE_OPENAI_API_KEY",
+		BaseURLEnv:    "AZURE_OPENAI_ENDPOINT",
+		DefaultModel:  "tts-1-hd",
+		DefaultVoice:  "alloy",
+		DefaultFormat: DefaultResponseFormat,
+	}, func(pc ProviderConfig) (TTSClient, error) {
+		if pc.BaseURL == "" || pc.Deployment == "" {
+			return nil, fmt.Errorf("provider azure-openai needs a base URL (https://<resource>.openai.azure.com) and a deployment")
+		}
+		c, err := newOpenAIClient(pc, "")
+		if err != nil {
+			return nil, err
+		}
+		c.requireKey, c.voices = true, openAIVoices
+		return c, nil
+	})
+
+	RegisterProvider(ProviderInfo{
+		Name:          "elevenlabs",
+		Description:   "ElevenLabs REST text-to-speech",
+		KeyEnv:        "ELEVENLABS_API_KEY",
+		DefaultModel:  "eleven_multilingual_v2",
+		DefaultVoice:  "21m00Tcm4TlvDq8ikWAM",
+		DefaultFormat: "mp3",
+	}, func(pc ProviderConfig) (TTSClient, error) {
+		base := elevenLabsBaseURL
+		if pc.BaseURL != "" {
+			base = strings.TrimRight(pc.BaseURL, "/")
+		}
+		return &elevenLabsClient{httpClient: pc.httpClient(), baseURL: base, headers: pc.Headers, retry: pc.Retry}, nil
+	})
+
+	RegisterProvider(ProviderInfo{
+		Name:          "command",
+		Description:   "Local engine run as a command: text on stdin, audio on stdout",
+		DefaultFormat: "wav",
+		Local:         true,
+	}, func(pc ProviderConfig) (TTSClient, error) {
+		return NewCommandClient(pc.Command, pc.CommandFormat)
+	})
+}
@@ -0,0 +1,216 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"slices"
+	"testing"
+)
+
+func TestProviderRegistry(t *testing.T) {
+	names := []string{}
+	for _, p := range Providers() {
+		names = append(names, p.Name)
+	}
+	if names[0] != DefaultProvider {
+		t.Fatalf("default provider should be listed first: %v", names)
+	}
+	for _, want := range []string{"openai-compatible", "elevenlabs", "command"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("provider %q is not registered", want)
+		}
+	}
+	if _, err := NewTTSClient("nope", ProviderConfig{}); err == nil {
+		t.Error("unknown provider should fail")
+	}
+	if _, err := NewTTSClient("openai-compatible", ProviderConfig{}); err == nil {
+		t.Error("openai-compatible without a base URL should fail")
+	}
+
+	client, err := NewTTSClient("", ProviderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(VoicesOf(client), "alloy") || !slices.Contains(FormatsOf(client), "aac") {
+		t.Fatalf("openai declares voices %v and formats %v", VoicesOf(client), FormatsOf(client))
+	}
+	eleven, _ := NewTTSClient("elevenlabs", ProviderConfig{})
+	if slices.Contains(FormatsOf(eleven), "aac") || ChunkLimit(Config{}, eleven).Max != 5000 {
+		t.Fatalf("elevenlabs declares formats %v and limit %v", FormatsOf(eleven), ChunkLimit(Config{}, eleven))
+	}
+}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	var gotPath, gotAuth string
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte("AUDIO"))
+	}))
+	defer srv.Close()
+
+	client, err := NewTTSClient("openai-compatible", ProviderConfig{BaseURL: srv.URL + "/v1/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := client.Synthesize(context.Background(), Config{Model: "kokoro", Voice: "af_sky", ResponseFormat: "mp3"}, "Hello.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio) != "AUDIO" || gotPath != "/v1/audio/speech" || gotAuth != "" {
+		t.Fatalf("audio %q, path %q, auth %q", audio, gotPath, gotAuth)
+	}
+	if payload["model"] != "kokoro" || payload["voice"] != "af_sky" || payload["input"] != "Hello." {
+		t.Fatalf("payload = %v", payload)
+	}
+}
+
+func TestElevenLabsProvider(t *testing.T) {
+	var gotPath, gotFormat, gotKey string
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotFormat, gotKey = r.URL.Path, r.URL.Query().Get("output_format"), r.Header.Get("xi-api-key")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+		_, _ = w.Write([]byte("MP3"))
+	}))
+	defer srv.Close()
+
+	client, err := NewTTSClient("elevenlabs", ProviderConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{APIKey: "k", Model: "eleven_multilingual_v2", Voice: "voice-id", ResponseFormat: "mp3", Speed: 1.1}
+	if _, err := client.Synthesize(context.Background(), cfg, "Hi."); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/v1/text-to-speech/voice-id" || gotFormat != "mp3_44100_128" || gotKey != "k" {
+		t.Fatalf("path %q, format %q, key %q", gotPath, gotFormat, gotKey)
+	}
+	if payload["text"] != "Hi." || payload["model_id"] != "eleven_multilingual_v2" || payload["voice_settings"] == nil {
+		t.Fatalf("payload = %v", payload)
+	}
+	if _, err := client.Synthesize(context.Background(), Config{Voice: "v", ResponseFormat: "mp3"}, "Hi."); err == nil {
+		t.Fatal("missing API key should fail")
+	}
+}
+
+func TestCommandProvider(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	client, err := NewTTSClient("command", ProviderConfig{Command: `sh -c 'printf "%s:" "$1"; cat' tts {voice}`, CommandFormat: "pcm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatsOf(client); !slices.Equal(got, []string{"pcm"}) {
+		t.Fatalf("formats = %v", got)
+	}
+	audio, err := client.Synthesize(context.Background(), Config{Voice: "en"}, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio) != "en:hello" {
+		t.Fatalf("audio = %q", audio)
+	}
+
+	failing, _ := NewCommandClient(`sh -c 'echo broken >&2; exit 3'`, "wav")
+	if _, err := failing.Synthesize(context.Background(), Config{}, "x"); err == nil {
+		t.Fatal("failing command should return an error")
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	got, err := splitCommand(`piper --model "en US.onnx" --output_file - 'a b' c\ d`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"piper", "--model", "en US.onnx", "--output_file", "-", "a b", "c d"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("splitCommand = %q, want %q", got, want)
+	}
+	if _, err := splitCommand(`say "unterminated`); err == nil {
+		t.Fatal("unterminated quote should fail")
+	}
+}
+
+func TestOpenAIClientEndpoint(t *testing.T) {
+	tests := []struct {
+		pc   ProviderConfig
+		want string
+	}{
+		{ProviderConfig{}, openAIEndpoint},
+		{ProviderConfig{BaseURL: "https://gateway.example/v1/"}, "https://gateway.example/v1/audio/speech"},
+		{ProviderConfig{BaseURL: "https://gateway.example", Path: "tts/speech"}, "https://gateway.example/tts/speech"},
+		{ProviderConfig{BaseURL: "https://r.openai.azure.com", Deployment: "tts hd"}, "https://r.openai.azure.com/openai/deployments/tts%20hd/audio/speech?api-version=" + azureAPIVersion},
+		{ProviderConfig{BaseURL: "https://r.openai.azure.com", Deployment: "tts", APIVersion: "2024-05-01"}, "https://r.openai.azure.com/openai/deployments/tts/audio/speech?api-version=2024-05-01"},
+	}
+	for _, tt := range tests {
+		c, err := newOpenAIClient(tt.pc, openAIBaseURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.endpoint != tt.want {
+			t.Errorf("endpoint for %+v = %q, want %q", tt.pc, c.endpoint, tt.want)
+		}
+	}
+	if _, err := newOpenAIClient(ProviderConfig{BaseURL: "localhost:8000"}, ""); err == nil {
+		t.Error("base URL without a scheme should fail")
+	}
+
+	t.Setenv("OPENAI_BASE_URL", "http://proxy.local/v1")
+	client, err := NewTTSClient("openai", ProviderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.(*openAIClient).endpoint; got != "http://proxy.local/v1/audio/speech" {
+		t.Errorf("OPENAI_BASE_URL endpoint = %q", got)
+	}
+}
+
+func TestAzureOpenAIProvider(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		_, _ = w.Write([]byte("AUDIO"))
+	}))
+	defer srv.Close()
+
+	if _, err := NewTTSClient("azure-openai", ProviderConfig{BaseURL: srv.URL}); err == nil {
+		t.Fatal("azure-openai without a deployment should fail")
+	}
+	client, err := NewTTSClient("azure-openai", ProviderConfig{
+		BaseURL:    srv.URL,
+		Deployment: "speech",
+		Headers:    map[string]string{"X-Team": "docs"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Synthesize(context.Background(), Config{APIKey: "k", Voice: "alloy", ResponseFormat: "aac"}, "Hi."); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Path != "/openai/deployments/speech/audio/speech" || got.URL.Query().Get("api-version") != azureAPIVersion {
+		t.Errorf("request URL = %s", got.URL)
+	}
+	if got.Header.Get("api-key") != "k" || got.Header.Get("Authorization") != "" || got.Header.Get("X-Team") != "docs" {
+		t.Errorf("request headers = %v", got.Header)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	name, value, err := ParseHeader(" X-Gateway-Key :  abc: def ")
+	if err != nil || name != "X-Gateway-Key" || value != "abc: def" {
+		t.Fatalf("ParseHeader = %q, %q, %v", name, value, err)
+	}
+	for _, bad := range []string{"no colon", ": value", "Bad Name: v"} {
+		if _, _, err := ParseHeader(bad); err == nil {
+			t.Errorf("ParseHeader(%q) should fail", bad)
+		}
+	}
+}
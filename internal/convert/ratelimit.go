@@ -0,0 +1,202 @@
+package convert
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// rateBurst is how much of a minute's allowance a limiter may spend at
+	// once after sitting idle.
+	rateBurst = 10 * time.Second
+	// rateRecovery is how long a throttled limiter must go without a 429
+	// before it raises its rate again.
+	rateRecovery = 30 * time.Second
+	// rateCooldown is how soon after one halving a 429 may halve the rate
+	// again, so that workers rejected together throttle it once.
+	rateCooldown = 5 * time.Second
+	// minRateScale is the lowest fraction of the configured rate a limiter
+	// throttles down to.
+	minRateScale = 1.0 / 16
+)
+
+// RateLimiter paces the requests of a run with two token buckets, one for
+// requests per minute and one for characters per minute. It is shared by
+// all workers of a run; HTTP providers wait on Config.RateLimit before every
+// request, retries included, and call Throttle when the provider answers
+// 429. A nil RateLimiter never waits.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests bucket
+	chars    bucket
+	// scale is the fraction of the buckets' rates in force.
+	scale float64
+	// refilled is when the buckets were last refilled, changed when scale
+	// last moved and throttled when a 429 last halved it.
+	refilled  time.Time
+	changed   time.Time
+	throttled time.Time
+	started   time.Time
+	granted   int
+	throttles int
+	waiting   int
+	now       func() time.Time
+}
+
+// bucket is a token bucket refilled at rate tokens per minute. Tokens may
+// go negative: a request larger than the bucket goes out when the bucket is
+// full, and later requests wait until its debt is paid off.
+type bucket struct {
+	rate   float64
+	tokens float64
+}
+
+func (b *bucket) capacity(scale float64) float64 {
+	return max(b.rate*scale*rateBurst.Minutes(), 1)
+}
+
+func (b *bucket) refill(scale float64, elapsed time.Duration) {
+	if b.rate > 0 {
+		b.tokens = min(b.tokens+b.rate*scale*elapsed.Minutes(), b.capacity(scale))
+	}
+}
+
+// take removes n tokens and returns how long the caller must wait before
+// using them: until the bucket would have held n tokens, or a full bucket
+// when n is larger than that.
+func (b *bucket) take(n, scale float64) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	need := min(n, b.capacity(scale))
+	var d time.Duration
+	if b.tokens < need {
+		d = time.Duration((need - b.tokens) / (b.rate * scale) * float64(time.Minute))
+	}
+	b.tokens -= n
+	return d
+}
+
+// NewRateLimiter returns a limiter allowing rpm requests and charsPerMin
+// characters a minute; zero leaves that dimension unlimited until a 429
+// teaches it a rate.
+func NewRateLimiter(rpm, charsPerMin int) *RateLimiter {
+	l := &RateLimiter{
+		requests: bucket{rate: float64(max(rpm, 0))},
+		chars:    bucket{rate: float64(max(charsPerMin, 0))},
+		scale:    1,
+		now:      time.Now,
+	}
+	l.started = l.now()
+	l.refilled, l.changed = l.started, l.started
+	l.requests.tokens = l.requests.capacity(1)
+	l.chars.tokens = l.chars.capacity(1)
+	return l
+}
+
+// advance refills the buckets up to now and, once the limiter has gone
+// rateRecovery without a 429, raises a throttled rate by a quarter.
+func (l *RateLimiter) advance(now time.Time) {
+	for l.scale < 1 && now.Sub(l.changed) >= rateRecovery {
+		l.changed = l.changed.Add(rateRecovery)
+		l.refillTo(l.changed)
+		l.scale = min(l.scale*1.25, 1)
+	}
+	l.refillTo(now)
+}
+
+func (l *RateLimiter) refillTo(t time.Time) {
+	if elapsed := t.Sub(l.refilled); elapsed > 0 {
+		l.requests.refill(l.scale, elapsed)
+		l.chars.refill(l.scale, elapsed)
+		l.refilled = t
+	}
+}
+
+// Wait blocks until a request of chars characters may be sent, or until ctx
+// is done.
+func (l *RateLimiter) Wait(ctx context.Context, chars int) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	l.advance(l.now())
+	d := max(l.requests.take(1, l.scale), l.chars.take(float64(chars), l.scale))
+	l.granted++
+	if d <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.waiting++
+	l.mu.Unlock()
+
+	err := sleep(ctx, d)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waiting--
+	if err != nil {
+		// The request will not be sent; give its tokens back.
+		l.requests.tokens++
+		l.chars.tokens += float64(chars)
+		l.granted--
+	}
+	return err
+}
+
+// Throttle halves the limiter's rate, down to a sixteenth of the configured
+// limits, after the provider answered 429. Without a requests-per-minute
+// limit it first adopts the request rate observed so far.
+func (l *RateLimiter) Throttle() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.advance(now)
+	l.throttles++
+	if !l.throttled.IsZero() && now.Sub(l.throttled) < rateCooldown {
+		return
+	}
+	if l.requests.rate == 0 {
+		l.requests.rate = float64(max(l.granted, 1)) / max(now.Sub(l.started).Minutes(), 1)
+	}
+	l.scale = max(l.scale/2, minRateScale)
+	l.changed, l.throttled = now, now
+	l.requests.tokens = min(l.requests.tokens, 0)
+	l.chars.tokens = min(l.chars.tokens, 0)
+}
+
+// RateStats is a snapshot of a RateLimiter.
+type RateStats struct {
+	// RPM and CharsPerMin are the limits in force now, after throttling;
+	// zero is unlimited.
+	RPM         float64
+	CharsPerMin float64
+	// Throttled reports that recent 429s hold the limits below their full
+	// rate; Throttles counts the 429s seen.
+	Throttled bool
+	Throttles int
+	// Waiting counts requests held back right now.
+	Waiting int
+}
+
+// Stats returns the limiter's current state; a nil limiter reports no
+// limits.
+func (l *RateLimiter) Stats() RateStats {
+	if l == nil {
+		return RateStats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.now())
+	return RateStats{
+		RPM:         l.requests.rate * l.scale,
+		CharsPerMin: l.chars.rate * l.scale,
+		Throttled:   l.scale < 1,
+		Throttles:   l.throttles,
+		Waiting:     l.waiting,
+	}
+}
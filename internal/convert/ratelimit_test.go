@@ -0,0 +1,117 @@
+package convert
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a limiter clock and a function that moves it forward.
+func fakeClock(l *RateLimiter) (advance func(time.Duration)) {
+	now := l.started
+	l.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestRateLimiterBuckets(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A cancelled context fails only when Wait would have to block.
+	requests := NewRateLimiter(60, 0)
+	tick := fakeClock(requests)
+	for i := range 10 {
+		if err := requests.Wait(cancelled, 4096); err != nil {
+			t.Fatalf("request %d of the burst waited: %v", i+1, err)
+		}
+	}
+	if err := requests.Wait(cancelled, 1); err == nil {
+		t.Fatal("request past the burst should wait")
+	}
+	tick(time.Second)
+	if err := requests.Wait(cancelled, 1); err != nil {
+		t.Fatalf("request a second later waited: %v", err)
+	}
+
+	chars := NewRateLimiter(0, 6000)
+	tick = fakeClock(chars)
+	if err := chars.Wait(cancelled, 5000); err != nil {
+		t.Fatalf("chunk larger than the burst should go out on a full bucket: %v", err)
+	}
+	if err := chars.Wait(cancelled, 1); err == nil {
+		t.Fatal("characters past the burst should wait")
+	}
+	tick(41 * time.Second)
+	if err := chars.Wait(cancelled, 10); err != nil {
+		t.Fatalf("characters after the debt is paid waited: %v", err)
+	}
+
+	var unlimited *RateLimiter
+	if err := unlimited.Wait(cancelled, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRateLimiterThrottle(t *testing.T) {
+	l := NewRateLimiter(60, 6000)
+	tick := fakeClock(l)
+
+	l.Throttle()
+	l.Throttle()
+	if s := l.Stats(); s.RPM != 30 || s.CharsPerMin != 3000 || !s.Throttled || s.Throttles != 2 {
+		t.Fatalf("after two quick 429s: %+v, want one halving", s)
+	}
+	tick(rateCooldown)
+	l.Throttle()
+	if s := l.Stats(); s.RPM != 15 {
+		t.Fatalf("after a later 429: %+v", s)
+	}
+	tick(rateRecovery)
+	if s := l.Stats(); s.RPM != 18.75 {
+		t.Fatalf("after %v without 429s: %+v", rateRecovery, s)
+	}
+	tick(10 * rateRecovery)
+	if s := l.Stats(); s.RPM != 60 || s.Throttled {
+		t.Fatalf("after recovering: %+v", s)
+	}
+
+	learned := NewRateLimiter(0, 0)
+	tick = fakeClock(learned)
+	for range 30 {
+		learned.Wait(context.Background(), 100)
+	}
+	tick(30 * time.Second)
+	learned.Throttle()
+	if s := learned.Stats(); s.RPM != 15 || s.CharsPerMin != 0 {
+		t.Fatalf("learned limits = %+v, want half of 30 requests a minute", s)
+	}
+}
+
+func TestPostWithRetryThrottlesLimiter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After-Ms", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("AUDIO"))
+	}))
+	defer srv.Close()
+
+	limit := NewRateLimiter(6000, 0)
+	c, err := newOpenAIClient(ProviderConfig{BaseURL: srv.URL, HTTPClient: srv.Client()}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Synthesize(context.Background(), Config{Voice: "alloy", RateLimit: limit}, "Hello.")
+	if err != nil || string(got) != "AUDIO" {
+		t.Fatalf("Synthesize = %q, %v", got, err)
+	}
+	if s := limit.Stats(); s.Throttles != 1 || s.RPM != 3000 {
+		t.Fatalf("limiter after a 429 = %+v", s)
+	}
+}
@@ -0,0 +1,172 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Defaults of RetryPolicy's zero fields.
+const (
+	DefaultRetryAttempts = 4
+	DefaultRetryBaseWait = 500 * time.Millisecond
+	DefaultRetryMaxWait  = time.Minute
+)
+
+// RetryPolicy says how HTTP providers retry a failed request. Rate limits
+// (429), server errors and network errors such as timeouts and connection
+// resets are retried; other errors fail at once. Zero fields take the
+// Default values above.
+type RetryPolicy struct {
+	// MaxAttempts counts the first request; 1 disables retries.
+	MaxAttempts int
+	// BaseWait is the backoff before the second attempt. It doubles with
+	// each attempt, with jitter, up to MaxWait.
+	BaseWait time.Duration
+	// MaxWait caps every wait, including one asked for by the server in a
+	// Retry-After or x-ratelimit-reset-* header.
+	MaxWait time.Duration
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+// wait returns how long to wait after the failed attempt'th request: the
+// server's requested delay when err carries one, otherwise jittered
+// exponential backoff.
+func (p RetryPolicy) wait(attempt int, err error) time.Duration {
+	base, maxWait := p.BaseWait, p.MaxWait
+	if base <= 0 {
+		base = DefaultRetryBaseWait
+	}
+	if maxWait <= 0 {
+		maxWait = DefaultRetryMaxWait
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.retryAfter > 0 {
+		return min(apiErr.retryAfter, maxWait)
+	}
+	d := base << min(attempt-1, 30)
+	if d <= 0 || d > maxWait {
+		d = maxWait
+	}
+	// Equal jitter: keep half the backoff and randomize the rest, so workers
+	// that failed together do not retry together.
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryable reports whether a request that failed with err may succeed when
+// sent again.
+func retryable(err error) bool {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.retryable
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// postWithRetry POSTs body, which carries chars characters of text, to url
+// and returns the response body. Every attempt waits on limit first; 429s
+// throttle it, and failed attempts are retried as policy allows.
+func postWithRetry(ctx context.Context, client *http.Client, policy RetryPolicy, limit *RateLimiter, chars int, url string, headers map[string]string, body []byte) ([]byte, error) {
+	attempts := policy.attempts()
+	for attempt := 1; ; attempt++ {
+		if err := limit.Wait(ctx, chars); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		err := doTTSRequest(ctx, client, url, headers, body, &buf)
+		if err == nil {
+			return buf.Bytes(), nil
+		}
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.code == http.StatusTooManyRequests {
+			limit.Throttle()
+		}
+		if ctx.Err() != nil || !retryable(err) {
+			return nil, err
+		}
+		if attempt == attempts {
+			if attempts > 1 {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+			}
+			return nil, err
+		}
+		if err := sleep(ctx, policy.wait(attempt, err)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter reads the delay a rate-limited response asks for: Retry-After
+// (seconds or an HTTP date), Azure's retry-after-ms, or else the latest of
+// OpenAI's x-ratelimit-reset-* headers ("1s", "6m0s", "20ms"). It returns
+// zero when the response names none.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	if v := strings.TrimSpace(h.Get("Retry-After-Ms")); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if s, err := strconv.ParseFloat(v, 64); err == nil {
+			return max(time.Duration(s*float64(time.Second)), 0)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now), 0)
+		}
+	}
+	var longest time.Duration
+	for name, values := range h {
+		if !strings.HasPrefix(strings.ToLower(name), "x-ratelimit-reset-") || len(values) == 0 {
+			continue
+		}
+		v := strings.TrimSpace(values[0])
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			s, ferr := strconv.ParseFloat(v, 64)
+			if ferr != nil {
+				continue
+			}
+			d = time.Duration(s * float64(time.Second))
+		}
+		longest = max(longest, d)
+	}
+	return longest
+}
@@ -0,0 +1,121 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		headers map[string]string
+		want    time.Duration
+	}{
+		{nil, 0},
+		{map[string]string{"Retry-After": "2"}, 2 * time.Second},
+		{map[string]string{"Retry-After": now.Add(3 * time.Second).Format(http.TimeFormat)}, 3 * time.Second},
+		{map[string]string{"Retry-After-Ms": "250", "Retry-After": "1"}, 250 * time.Millisecond},
+		{map[string]string{"X-Ratelimit-Reset-Requests": "20ms", "X-Ratelimit-Reset-Tokens": "1m6s"}, 66 * time.Second},
+		{map[string]string{"X-Ratelimit-Reset-Requests": "1.5"}, 1500 * time.Millisecond},
+		{map[string]string{"X-Ratelimit-Reset": "1740830400"}, 0},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.headers {
+			h.Set(k, v)
+		}
+		if got := retryAfter(h, now); got != tt.want {
+			t.Errorf("retryAfter(%v) = %v, want %v", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	p := RetryPolicy{BaseWait: 100 * time.Millisecond, MaxWait: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		for range 20 {
+			if got := p.wait(attempt, errors.New("reset")); got < want/2 || got > want {
+				t.Fatalf("wait(%d) = %v, want between %v and %v", attempt, got, want/2, want)
+			}
+		}
+	}
+	limited := &apiError{retryable: true, retryAfter: 300 * time.Millisecond}
+	if got := p.wait(1, limited); got != 300*time.Millisecond {
+		t.Errorf("wait with Retry-After = %v", got)
+	}
+	limited.retryAfter = time.Hour
+	if got := p.wait(1, limited); got != time.Second {
+		t.Errorf("wait past MaxWait = %v", got)
+	}
+}
+
+func TestPostWithRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			// Drop the connection without answering.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case 2:
+			w.Header().Set("X-Ratelimit-Reset-Requests", "30ms")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 3:
+			http.Error(w, "oops", http.StatusBadGateway)
+		default:
+			w.Write([]byte("AUDIO"))
+		}
+	}))
+	defer srv.Close()
+	policy := RetryPolicy{BaseWait: time.Millisecond}
+
+	start := time.Now()
+	got, err := postWithRetry(context.Background(), srv.Client(), policy, nil, 0, srv.URL, nil, []byte("{}"))
+	if err != nil || string(got) != "AUDIO" || calls.Load() != 4 {
+		t.Fatalf("postWithRetry = %q, %v after %d calls", got, err, calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("retried after %v, before the rate limit reset", elapsed)
+	}
+
+	calls.Store(1)
+	policy.MaxAttempts = 2
+	_, err = postWithRetry(context.Background(), srv.Client(), policy, nil, 0, srv.URL, nil, []byte("{}"))
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "giving up after 2 attempts") || calls.Load() != 3 {
+		t.Fatalf("exhausted retries = %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestPostWithRetryStops(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/bad" {
+			http.Error(w, "bad voice", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	policy := RetryPolicy{MaxWait: time.Minute}
+
+	if _, err := postWithRetry(context.Background(), srv.Client(), policy, nil, 0, srv.URL+"/bad", nil, nil); err == nil || calls.Load() != 1 {
+		t.Fatalf("client error = %v after %d calls, want no retry", err, calls.Load())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := postWithRetry(ctx, srv.Client(), policy, nil, 0, srv.URL, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Fatalf("cancelled wait = %v after %v", err, time.Since(start))
+	}
+}
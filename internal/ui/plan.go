@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/markloud/markloud"
+)
+
+// planFilesShown caps the file rows on the TUI plan screen.
+const planFilesShown = 8
+
+// writePlan prints a dry-run plan as a table followed by totals and costs.
+func writePlan(w io.Writer, plan markloud.Plan, cfg markloud.Config) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tCHUNKS\tCHARS\tAUDIO\tSTATUS")
+	for _, fp := range plan.Files {
+		if fp.Skip != "" {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t%s\n", fp.Job.RelPath, planStatus(fp))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", fp.Job.RelPath, fp.Chunks, fp.Chars, formatDuration(fp.Duration), planStatus(fp))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	for _, line := range planTotals(plan, cfg) {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// planStatus describes what the run would do with one file.
+func planStatus(fp markloud.FilePlan) string {
+	switch {
+	case fp.Skip == markloud.JobFailed:
+		return "error: " + fp.Reason
+	case fp.Skip != "":
+		return fmt.Sprintf("%s (%s)", fp.Skip, fp.Reason)
+	case fp.Cached > 0:
+		return fmt.Sprintf("convert (%d cached)", fp.Cached)
+	default:
+		return "convert"
+	}
+}
+
+// planTotals summarises a plan and estimates its cost for every priced model.
+func planTotals(plan markloud.Plan, cfg markloud.Config) []string {
+	lines := []string{
+		fmt.Sprintf("%d to convert, %d skipped, %d failed", plan.Convert, plan.Skipped, plan.Failed),
+		fmt.Sprintf("%d chunks, %d characters (%d billed), about %s of audio",
+			plan.Chunks, plan.Chars, plan.BilledChars, formatDuration(plan.Duration)),
+	}
+	if provider, _ := markloud.LookupProvider(cfg.Provider); provider.Local {
+		return append(lines, fmt.Sprintf("Estimated cost: none (%s runs locally)", provider.Name))
+	}
+	lines = append(lines, "Estimated cost:")
+	selected, _ := markloud.PriceFor(cfg.Model)
+	for _, p := range markloud.ModelPrices() {
+		line := fmt.Sprintf("  %-16s $%.3f", p.Model, p.Cost(plan.BilledChars))
+		if p.Model == selected.Model {
+			line += "  ← " + cfg.Model
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatDuration rounds d to whole seconds for display.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+func (m *model) viewPlan() string {
+	title := "Plan"
+	if m.cliOpts.DryRun {
+		title = "Dry run"
+	}
+	lines := []string{titleStyle.Render(fmt.Sprintf("%s — %s", m.versionLabel(), title))}
+	for i, fp := range m.plan.Files {
+		if i == planFilesShown {
+			lines = append(lines, dimStyle.Render(fmt.Sprintf("… and %d more files", len(m.plan.Files)-i)))
+			break
+		}
+		detail := planStatus(fp)
+		if fp.Skip == "" {
+			detail = fmt.Sprintf("%d chunks · %d chars · %s · %s", fp.Chunks, fp.Chars, formatDuration(fp.Duration), detail)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", valueStyle.Render(truncate(fp.Job.RelPath, 32)), dimStyle.Render(detail)))
+	}
+	lines = append(lines, "")
+	for _, line := range planTotals(m.plan, m.cfg) {
+		lines = append(lines, labelStyle.Render(line))
+	}
+	lines = append(lines, "")
+	switch {
+	case m.cliOpts.DryRun:
+		lines = append(lines, emphStyle.Render("Dry run: esc to go back, q to quit."))
+	case m.plan.Convert == 0:
+		lines = append(lines, emphStyle.Render("Nothing to convert: esc to go back, q to quit."))
+	default:
+		lines = append(lines, emphStyle.Render("Press enter to start, esc to go back, q to quit."))
+	}
+	return boxStyle.Width(76).Render(strings.Join(lines, "\n"))
+}
+
+// truncate shortens s to at most n runes, keeping its end.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return "…" + string(r[len(r)-n+1:])
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -16,13 +17,14 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/markloud/markloud/internal/convert"
+	"github.com/markloud/markloud"
 )
 
 type appState int
 
 const (
 	stateConfig appState = iota
+	statePlan
 	stateRunning
 	stateDone
 	stateError
@@ -33,23 +35,30 @@ type summaryCounts struct {
 	Skipped int
 	Empty   int
 	Failed  int
+	// Resumed counts chunks reused from checkpoints of earlier runs.
+	Resumed int
+	// CacheHits and CacheMisses count chunk lookups in the audio cache.
+	CacheHits   int
+	CacheMisses int
+	// NotAttempted counts files left untouched after the run was stopped.
+	NotAttempted int
 }
 
 type preparedMsg struct {
-	cfg  convert.Config
-	jobs []convert.FileJob
+	conv *markloud.Converter
+	jobs []markloud.FileJob
+	plan markloud.Plan
 }
 
 type prepareFailedMsg struct{ err error }
 
 type fileDoneMsg struct {
-	idx int
-	res convert.JobResult
-	job convert.FileJob
+	res markloud.JobResult
+	job markloud.FileJob
 }
 
 type chunkMsg struct {
-	job   convert.FileJob
+	job   markloud.FileJob
 	idx   int
 	total int
 	done  bool
@@ -58,11 +67,54 @@ type chunkMsg struct {
 
 type allDoneMsg struct{}
 
+// CLIOptions carries settings from command-line flags. A non-empty InputDir
+// starts the conversion immediately (CLI mode); the remaining fields also
+// apply to runs started from the TUI.
 type CLIOptions struct {
-	InputDir  string
-	OutputDir string
-	Voice     string
-	Overwrite bool
+	InputDir     string
+	OutputDir    string
+	Voice        string
+	Overwrite    bool
+	Update       bool
+	Tables       markloud.TableMode
+	TableMaxRows int
+	CodeBlocks   markloud.CodeBlockMode
+	Lexicon      string
+	Locale       string
+	URLs         markloud.URLMode
+	ChunkSize    int
+	SectionLevel int
+	HeadingCues  map[int]string
+	Format       string
+	WorkDir      string
+	// Provider selects the TTS provider; the fields below configure it (see
+	// markloud.ProviderConfig).
+	Provider      string
+	BaseURL       string
+	APIPath       string
+	Headers       map[string]string
+	APIVersion    string
+	Deployment    string
+	Command       string
+	CommandFormat string
+	// Retries and RetryMaxWait set the retry policy of HTTP providers; zero
+	// means markloud's defaults.
+	Retries      int
+	RetryMaxWait time.Duration
+	// RPM and CharsPerMin pace requests across workers; zero is unlimited.
+	RPM         int
+	CharsPerMin int
+	// CacheDir overrides markloud.DefaultCacheDir; NoCache disables the cache.
+	CacheDir      string
+	CacheMaxBytes int64
+	NoCache       bool
+	// MaxChars, MaxCost and MaxFiles cap the run; zero means no limit.
+	MaxChars int
+	MaxCost  float64
+	MaxFiles int
+	// DryRun stops after the plan: CLI mode prints it, the TUI shows it
+	// without starting the run.
+	DryRun bool
 }
 
 type VersionInfo struct {
@@ -76,11 +128,19 @@ type model struct {
 	inputs     []textinput.Model
 	focusIndex int
 	overwrite  bool
+	update     bool
 	message    string
 	err        error
 
-	cfg          convert.Config
-	jobs         []convert.FileJob
+	// conv runs the prepared conversion; cfg is its Config.
+	conv *markloud.Converter
+	cfg  markloud.Config
+	plan markloud.Plan
+	// notAttempted lists files the run never started; stopReason says why
+	// the run stopped early, e.g. a budget limit.
+	notAttempted []string
+	stopReason   string
+	jobs         []markloud.FileJob
 	currentIdx   int
 	summary      summaryCounts
 	currentChunk string
@@ -89,9 +149,9 @@ type model struct {
 	cancel       context.CancelFunc
 	version      VersionInfo
 
-	workerSem chan struct{}
-	chunkCh   chan chunkMsg
-	tasks     map[string]taskStatus
+	// events carries chunk and file progress from the running conversion.
+	events chan tea.Msg
+	tasks  map[string]taskStatus
 
 	logFile *os.File
 	logPath string
@@ -99,6 +159,16 @@ type model struct {
 
 	spin spinner.Model
 
+	// providers are the selectable TTS providers; providerIdx is the chosen one.
+	providers   []markloud.ProviderInfo
+	providerIdx int
+	// defaultVoice is the voice prefilled for the selected provider.
+	defaultVoice string
+	// formats are the selected provider's output formats; formatIdx is the
+	// chosen one.
+	formats   []string
+	formatIdx int
+
 	// CLI mode - skip config screen and auto-quit on completion
 	cliMode bool
 	cliOpts *CLIOptions
@@ -115,6 +185,14 @@ type taskStatus struct {
 // Run launches the Bubble Tea UI with optional CLI defaults and version info.
 func Run(opts *CLIOptions, v VersionInfo) error {
 	m := initialModel(opts, v)
+	if m.cliMode && opts.DryRun {
+		conv, jobs, err := prepareRun(m.newConfig(), opts)
+		if err != nil {
+			return err
+		}
+		writePlan(os.Stdout, conv.Plan(jobs), conv.Config())
+		return nil
+	}
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err := p.Run()
 	return err
@@ -131,10 +209,15 @@ func initialModel(opts *CLIOptions, v VersionInfo) *model {
 	tiOut.SetValue("./audio_out")
 
 	tiVoice := textinput.New()
-	tiVoice.Placeholder = "alloy"
-	tiVoice.SetValue(envOr("OPENAI_TTS_VOICE", "alloy"))
+	tiVoice.Placeholder = "provider default"
+
+	tiBaseURL := textinput.New()
+	tiBaseURL.Placeholder = "provider default"
 
-	inputs := []textinput.Model{tiRoot, tiOut, tiVoice}
+	tiDeployment := textinput.New()
+	tiDeployment.Placeholder = "Azure OpenAI only"
+
+	inputs := []textinput.Model{tiRoot, tiOut, tiVoice, tiBaseURL, tiDeployment}
 	for i := range inputs {
 		if i == 0 {
 			inputs[i].Focus()
@@ -159,14 +242,31 @@ func initialModel(opts *CLIOptions, v VersionInfo) *model {
 		version:    v,
 	}
 
+	if opts == nil {
+		opts = &CLIOptions{}
+	}
+	m.cliOpts = opts
+	m.inputs[3].SetValue(opts.BaseURL)
+	m.inputs[4].SetValue(opts.Deployment)
+
+	m.providers = markloud.Providers()
+	for i, p := range m.providers {
+		if p.Name == opts.Provider {
+			m.providerIdx = i
+		}
+	}
+	m.selectProvider(opts.Format)
+	if opts.Voice != "" {
+		m.inputs[2].SetValue(opts.Voice)
+	}
+
 	// CLI mode: pre-fill inputs and mark for auto-start
-	if opts != nil {
+	if opts.InputDir != "" {
 		m.cliMode = true
-		m.cliOpts = opts
 		m.inputs[0].SetValue(opts.InputDir)
 		m.inputs[1].SetValue(opts.OutputDir)
-		m.inputs[2].SetValue(opts.Voice)
 		m.overwrite = opts.Overwrite
+		m.update = opts.Update
 	}
 
 	return m
@@ -187,27 +287,176 @@ func (m *model) Init() tea.Cmd {
 }
 
 func (m *model) startConversionCmd() tea.Cmd {
-	root := strings.TrimSpace(m.inputs[0].Value())
-	out := strings.TrimSpace(m.inputs[1].Value())
+	return prepareConversionCmd(m.newConfig(), m.runOptions())
+}
+
+// runOptions returns the CLI options with the provider settings edited in
+// the form.
+func (m *model) runOptions() *CLIOptions {
+	opts := *m.cliOpts
+	opts.BaseURL = strings.TrimSpace(m.inputs[3].Value())
+	opts.Deployment = strings.TrimSpace(m.inputs[4].Value())
+	return &opts
+}
+
+// newConfig builds the run configuration from the form inputs and CLI options.
+func (m *model) newConfig() markloud.Config {
+	provider := m.provider()
 	voice := strings.TrimSpace(m.inputs[2].Value())
 	if voice == "" {
-		voice = "alloy"
+		voice = provider.DefaultVoice
+	}
+	apiKey := ""
+	if provider.KeyEnv != "" {
+		apiKey = strings.TrimSpace(os.Getenv(provider.KeyEnv))
 	}
 
-	cfg := convert.Config{
-		Root:           root,
-		Out:            out,
+	return markloud.Config{
+		Root:           strings.TrimSpace(m.inputs[0].Value()),
+		Out:            strings.TrimSpace(m.inputs[1].Value()),
+		Provider:       provider.Name,
 		Voice:          voice,
-		Model:          "tts-1-hd-1106",
-		ResponseFormat: "aac",
+		Model:          provider.DefaultModel,
+		ResponseFormat: m.format(),
 		Speed:          1.0,
 		Overwrite:      m.overwrite,
+		Update:         m.update,
 		Instructions:   envOr("OPENAI_TTS_INSTRUCTIONS", "Speak clearly for podcast listening."),
-		APIKey:         strings.TrimSpace(os.Getenv("OPENAI_API_KEY")),
+		APIKey:         apiKey,
 		Pattern:        "*.md",
+		Tables:         m.cliOpts.Tables,
+		TableMaxRows:   m.cliOpts.TableMaxRows,
+		CodeBlocks:     m.cliOpts.CodeBlocks,
+		LexiconPath:    m.cliOpts.Lexicon,
+		Locale:         m.cliOpts.Locale,
+		URLs:           m.cliOpts.URLs,
+		ChunkSize:      m.cliOpts.ChunkSize,
+		SectionLevel:   m.cliOpts.SectionLevel,
+		HeadingCues:    m.cliOpts.HeadingCues,
+		WorkDir:        m.cliOpts.WorkDir,
+	}
+}
+
+// provider returns the selected TTS provider.
+func (m *model) provider() markloud.ProviderInfo {
+	if m.providerIdx < 0 || m.providerIdx >= len(m.providers) {
+		info, _ := markloud.LookupProvider(markloud.DefaultProvider)
+		return info
+	}
+	return m.providers[m.providerIdx]
+}
+
+// selectProvider loads the formats of the selected provider and picks
+// format, or the provider's default when it does not produce format. A voice
+// left at the previous provider's default follows the new provider.
+func (m *model) selectProvider(format string) {
+	provider := m.provider()
+	formats, err := markloud.ProviderFormats(provider.Name, m.runOptions().ProviderConfig())
+	if err != nil {
+		formats = []string{provider.DefaultFormat}
+	}
+	m.formats = formats
+	if !slices.Contains(m.formats, format) {
+		format = provider.DefaultFormat
+	}
+	m.formatIdx = max(slices.Index(m.formats, format), 0)
+
+	voice := strings.TrimSpace(m.inputs[2].Value())
+	if voice == "" || voice == m.defaultVoice {
+		m.inputs[2].SetValue(provider.DefaultVoice)
+	}
+	m.defaultVoice = provider.DefaultVoice
+}
+
+// Focus order: input directory, output directory and voice, then the
+// provider and format selectors, then the provider's base URL and deployment.
+const (
+	focusProvider = 3
+	focusFormat   = 4
+)
+
+// inputAt returns the text input at focus position i, or -1 for a selector.
+func inputAt(i int) int {
+	switch {
+	case i < focusProvider:
+		return i
+	case i > focusFormat:
+		return i - 2
+	}
+	return -1
+}
+
+// typing reports whether a text input has focus, in which case printable
+// keys are typed rather than taken as commands.
+func (m *model) typing() bool {
+	return inputAt(m.focusIndex) >= 0
+}
+
+// providerFocused reports whether the provider selector has focus.
+func (m *model) providerFocused() bool {
+	return m.focusIndex == focusProvider
+}
+
+// format returns the selected output format.
+func (m *model) format() string {
+	if m.formatIdx < 0 || m.formatIdx >= len(m.formats) {
+		return markloud.DefaultResponseFormat
+	}
+	return m.formats[m.formatIdx]
+}
+
+// formatFocused reports whether the format selector has focus.
+func (m *model) formatFocused() bool {
+	return m.focusIndex == focusFormat
+}
+
+// ProviderConfig returns the provider settings carried by the options.
+func (o *CLIOptions) ProviderConfig() markloud.ProviderConfig {
+	return markloud.ProviderConfig{
+		BaseURL:       o.BaseURL,
+		Path:          o.APIPath,
+		Headers:       o.Headers,
+		APIVersion:    o.APIVersion,
+		Deployment:    o.Deployment,
+		Command:       o.Command,
+		CommandFormat: o.CommandFormat,
+		Retry:         markloud.RetryPolicy{MaxAttempts: o.Retries, MaxWait: o.RetryMaxWait},
+	}
+}
+
+// startRun converts the prepared jobs in the background.
+func (m *model) startRun() (tea.Model, tea.Cmd) {
+	m.state = stateRunning
+	m.currentIdx = 0
+	m.summary = summaryCounts{}
+	m.currentChunk = "waiting…"
+	m.lastError = ""
+	m.notAttempted = nil
+	m.stopReason = ""
+	m.tasks = make(map[string]taskStatus)
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	// Set up error log file if not already set
+	if m.logFile == nil {
+		cwd, _ := os.Getwd()
+		logPath := filepath.Join(cwd, "logs", "markloud_errors.log")
+		_ = os.MkdirAll(filepath.Dir(logPath), 0o755)
+		if logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err == nil {
+			m.logFile = logFile
+			m.logPath = logPath
+			fmt.Fprintf(logFile, "\n=== MarkLoud run %s ===\n", time.Now().Format(time.RFC3339))
+		}
 	}
 
-	return prepareConversionCmd(cfg)
+	workers := runtime.NumCPU() - 2
+	if workers < 1 {
+		workers = 1
+	}
+	m.events = make(chan tea.Msg, 100)
+	return m, tea.Batch(m.spin.Tick, listenEvents(m.events), convertAllCmd(m.ctx, m.conv, m.jobs, workers, m.events))
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -215,43 +464,16 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		return m.handleKey(msg)
 	case preparedMsg:
-		m.state = stateRunning
-		m.cfg = msg.cfg
+		m.conv = msg.conv
+		m.cfg = msg.conv.Config()
 		m.jobs = msg.jobs
-		m.currentIdx = 0
-		m.summary = summaryCounts{}
-		m.currentChunk = "waiting…"
-		m.lastError = ""
-		m.tasks = make(map[string]taskStatus)
-		if m.cancel != nil {
-			m.cancel()
-		}
-		m.ctx, m.cancel = context.WithCancel(context.Background())
-
-		// Set up error log file if not already set
-		if m.logFile == nil {
-			cwd, _ := os.Getwd()
-			logPath := filepath.Join(cwd, "logs", "markloud_errors.log")
-			_ = os.MkdirAll(filepath.Dir(logPath), 0o755)
-			if logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err == nil {
-				m.logFile = logFile
-				m.logPath = logPath
-				fmt.Fprintf(logFile, "\n=== MarkLoud run %s ===\n", time.Now().Format(time.RFC3339))
-			}
-		}
-
-		workers := runtime.NumCPU() - 2
-		if workers < 1 {
-			workers = 1
-		}
-		m.workerSem = make(chan struct{}, workers)
-		m.chunkCh = make(chan chunkMsg, 100)
-
-		cmds := []tea.Cmd{m.spin.Tick, listenChunks(m.chunkCh)}
-		for idx, job := range msg.jobs {
-			cmds = append(cmds, runJobCmd(m.ctx, msg.cfg, job, idx, m.workerSem, m.chunkCh))
+		m.plan = msg.plan
+		m.message = ""
+		if !m.cliMode {
+			m.state = statePlan
+			return m, nil
 		}
-		return m, tea.Batch(cmds...)
+		return m.startRun()
 	case prepareFailedMsg:
 		m.err = msg.err
 		m.message = ""
@@ -266,7 +488,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.currentIdx >= len(m.jobs) {
 			return m, func() tea.Msg { return allDoneMsg{} }
 		}
-		return m, nil
+		return m, listenEvents(m.events)
 	case allDoneMsg:
 		m.state = stateDone
 		m.message = "Conversion finished."
@@ -304,7 +526,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.currentChunk = fmt.Sprintf("%s (%d/%d)", msg.job.RelPath, msg.idx, msg.total)
 		if m.state == stateRunning {
-			return m, listenChunks(m.chunkCh)
+			return m, listenEvents(m.events)
 		}
 		return m, nil
 	case spinner.TickMsg:
@@ -321,12 +543,17 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.state {
 	case stateConfig:
 		switch msg.String() {
-		case "ctrl+c", "q":
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q":
+			if m.typing() {
+				return m.updateInputs(msg)
+			}
 			return m, tea.Quit
 		case "tab", "shift+tab", "up", "down":
-			m.focusIndex = nextFocus(msg.String(), m.focusIndex, len(m.inputs))
+			m.focusIndex = nextFocus(msg.String(), m.focusIndex, len(m.inputs)+2)
 			for i := range m.inputs {
-				if i == m.focusIndex {
+				if i == inputAt(m.focusIndex) {
 					m.inputs[i].Focus()
 					m.inputs[i].PromptStyle = focusedStyle
 					m.inputs[i].TextStyle = focusedStyle
@@ -337,14 +564,58 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				}
 			}
 			return m, nil
-		case "o":
-			m.overwrite = !m.overwrite
+		case "o", "ctrl+o":
+			if msg.String() == "o" && m.typing() {
+				return m.updateInputs(msg)
+			}
+			// Cycle existing audio handling: skip → update changed → overwrite.
+			switch {
+			case m.overwrite:
+				m.overwrite = false
+			case m.update:
+				m.update, m.overwrite = false, true
+			default:
+				m.update = true
+			}
+			return m, nil
+		case "left", "right":
+			step := 1
+			if msg.String() == "left" {
+				step = -1
+			}
+			switch {
+			case m.providerFocused() && len(m.providers) > 0:
+				format := m.format()
+				m.providerIdx = (m.providerIdx + step + len(m.providers)) % len(m.providers)
+				m.selectProvider(format)
+			case m.formatFocused() && len(m.formats) > 0:
+				m.formatIdx = (m.formatIdx + step + len(m.formats)) % len(m.formats)
+			default:
+				return m.updateInputs(msg)
+			}
 			return m, nil
 		case "enter":
 			return m.startConversion()
 		default:
 			return m.updateInputs(msg)
 		}
+	case statePlan:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "esc":
+			m.state = stateConfig
+			if m.logFile != nil {
+				m.logFile.Close()
+				m.logFile = nil
+			}
+			return m, textinput.Blink
+		case "enter":
+			if m.cliOpts.DryRun || m.plan.Convert == 0 {
+				return m, nil
+			}
+			return m.startRun()
+		}
 	case stateRunning:
 		if msg.String() == "ctrl+c" || msg.String() == "q" {
 			if m.cancel != nil {
@@ -402,13 +673,6 @@ func nextFocus(key string, current, total int) int {
 }
 
 func (m *model) startConversion() (tea.Model, tea.Cmd) {
-	root := strings.TrimSpace(m.inputs[0].Value())
-	out := strings.TrimSpace(m.inputs[1].Value())
-	voice := strings.TrimSpace(m.inputs[2].Value())
-	if voice == "" {
-		voice = "alloy"
-	}
-
 	cwd, _ := os.Getwd()
 	logPath := filepath.Join(cwd, "logs", "markloud_errors.log")
 	_ = os.MkdirAll(filepath.Dir(logPath), 0o755)
@@ -419,66 +683,94 @@ func (m *model) startConversion() (tea.Model, tea.Cmd) {
 	}
 	fmt.Fprintf(logFile, "\n=== MarkLoud run %s ===\n", time.Now().Format(time.RFC3339))
 
-	cfg := convert.Config{
-		Root:           root,
-		Out:            out,
-		Voice:          voice,
-		Model:          "tts-1-hd-1106",
-		ResponseFormat: "aac",
-		Speed:          1.0,
-		Overwrite:      m.overwrite,
-		Instructions:   envOr("OPENAI_TTS_INSTRUCTIONS", "Speak clearly for podcast listening."),
-		APIKey:         strings.TrimSpace(os.Getenv("OPENAI_API_KEY")),
-		Pattern:        "*.md",
-	}
+	cfg := m.newConfig()
 
 	m.err = nil
 	m.message = "Preparing files…"
 	m.logFile = logFile
 	m.logPath = logPath
-	return m, prepareConversionCmd(cfg)
+	return m, prepareConversionCmd(cfg, m.runOptions())
 }
 
-func prepareConversionCmd(cfg convert.Config) tea.Cmd {
+func prepareConversionCmd(cfg markloud.Config, opts *CLIOptions) tea.Cmd {
 	return func() tea.Msg {
-		if cfg.APIKey == "" {
-			return prepareFailedMsg{errors.New("OPENAI_API_KEY is not set")}
-		}
-		info, err := os.Stat(cfg.Root)
-		if err != nil || !info.IsDir() {
-			return prepareFailedMsg{fmt.Errorf("input directory not found: %s", cfg.Root)}
-		}
-		jobs, err := convert.CollectMarkdownFiles(cfg.Root, cfg.Out, cfg.Pattern, cfg.ResponseFormat)
+		conv, jobs, err := prepareRun(cfg, opts)
 		if err != nil {
 			return prepareFailedMsg{err}
 		}
-		if len(jobs) == 0 {
-			return prepareFailedMsg{fmt.Errorf("no markdown files matching %s", cfg.Pattern)}
+		return preparedMsg{conv: conv, jobs: jobs, plan: conv.Plan(jobs)}
+	}
+}
+
+// prepareRun checks the API key, opens the cache, budget and rate limiter,
+// and returns the Converter and jobs for a run.
+func prepareRun(cfg markloud.Config, opts *CLIOptions) (*markloud.Converter, []markloud.FileJob, error) {
+	provider, ok := markloud.LookupProvider(cfg.Provider)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+	}
+	if provider.KeyEnv != "" && !provider.KeyOptional && cfg.APIKey == "" && !opts.DryRun {
+		return nil, nil, fmt.Errorf("%s is not set", provider.KeyEnv)
+	}
+	var err error
+	if !opts.NoCache {
+		dir := opts.CacheDir
+		if dir == "" {
+			if dir, err = markloud.DefaultCacheDir(); err != nil {
+				return nil, nil, err
+			}
+		}
+		if cfg.Cache, err = markloud.OpenCache(dir, opts.CacheMaxBytes); err != nil {
+			return nil, nil, err
 		}
-		return preparedMsg{cfg: cfg, jobs: jobs}
 	}
+	maxCost := opts.MaxCost
+	if provider.Local {
+		// Local engines cost nothing, so a cost limit never binds.
+		maxCost = 0
+	}
+	if opts.MaxChars > 0 || maxCost > 0 || opts.MaxFiles > 0 {
+		if cfg.Budget, err = markloud.NewBudget(opts.MaxChars, maxCost, opts.MaxFiles, cfg.Model); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !provider.Local {
+		cfg.RateLimit = markloud.NewRateLimiter(opts.RPM, opts.CharsPerMin)
+	}
+	conv, err := markloud.New(cfg, markloud.WithProviderConfig(opts.ProviderConfig()))
+	if err != nil {
+		return nil, nil, err
+	}
+	jobs, err := conv.Collect()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil, fmt.Errorf("no markdown files matching %s", conv.Config().Pattern)
+	}
+	return conv, jobs, nil
 }
 
-func runJobCmd(ctx context.Context, cfg convert.Config, job convert.FileJob, idx int, sem chan struct{}, chunkCh chan<- chunkMsg) tea.Cmd {
+// convertAllCmd converts jobs, sending a chunkMsg before every chunk and a
+// chunkMsg and fileDoneMsg after every file to events.
+func convertAllCmd(ctx context.Context, conv *markloud.Converter, jobs []markloud.FileJob, workers int, events chan<- tea.Msg) tea.Cmd {
 	return func() tea.Msg {
-		sem <- struct{}{}
-		defer func() { <-sem }()
-
-		res := convert.ProcessFile(ctx, job, cfg, func(cur, total int) {
-			chunkCh <- chunkMsg{job: job, idx: cur, total: total}
+		conv.ConvertAll(ctx, jobs, workers, markloud.Progress{
+			Chunk: func(job markloud.FileJob, current, total int) {
+				events <- chunkMsg{job: job, idx: current, total: total}
+			},
+			File: func(job markloud.FileJob, res markloud.JobResult) {
+				events <- chunkMsg{job: job, idx: res.Chunks, total: res.Chunks, done: true, err: res.Err}
+				events <- fileDoneMsg{res: res, job: job}
+			},
 		})
-		chunkCh <- chunkMsg{job: job, idx: res.Chunks, total: res.Chunks, done: true, err: res.Err}
-		return fileDoneMsg{idx: idx, res: res, job: job}
+		return nil
 	}
 }
 
-func listenChunks(ch <-chan chunkMsg) tea.Cmd {
+func listenEvents(ch <-chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
-		msg, ok := <-ch
-		if !ok {
-			return nil
-		}
-		return msg
+		return <-ch
 	}
 }
 
@@ -486,25 +778,37 @@ func (m *model) applyResult(msg fileDoneMsg) {
 	ts := m.tasks[msg.job.RelPath]
 	ts.name = msg.job.RelPath
 	switch msg.res.Status {
-	case convert.JobDone:
+	case markloud.JobDone:
 		m.summary.Done++
 		ts.status = "done"
-	case convert.JobSkipped:
+	case markloud.JobSkipped:
 		m.summary.Skipped++
 		m.currentChunk = fmt.Sprintf("%s (skipped)", msg.job.RelPath)
 		ts.status = "skipped"
-	case convert.JobEmpty:
+	case markloud.JobEmpty:
 		m.summary.Empty++
 		m.currentChunk = fmt.Sprintf("%s (empty)", msg.job.RelPath)
 		ts.status = "empty"
-	case convert.JobFailed:
+	case markloud.JobFailed:
 		m.summary.Failed++
 		ts.status = "error"
 		ts.err = msg.res.Err
+	case markloud.JobNotAttempted:
+		m.summary.NotAttempted++
+		ts.status = "not attempted"
+		m.notAttempted = append(m.notAttempted, msg.job.RelPath)
 	}
+	m.summary.Resumed += msg.res.Resumed
+	m.summary.CacheHits += msg.res.Cache.Hits
+	m.summary.CacheMisses += msg.res.Cache.Misses
 	m.tasks[msg.job.RelPath] = ts
 	m.currentIdx++
-	if msg.res.Err != nil {
+	if errors.Is(msg.res.Err, markloud.ErrBudgetExceeded) {
+		m.stopReason = msg.res.Err.Error()
+	}
+	if msg.res.Status == markloud.JobNotAttempted {
+		m.logf("NOT ATTEMPTED %s: %v\n", msg.job.RelPath, msg.res.Err)
+	} else if msg.res.Err != nil {
 		m.currentChunk = fmt.Sprintf("%s (error)", msg.job.RelPath)
 		m.lastError = msg.res.Err.Error()
 		m.logf("ERROR %s: %v\n", msg.job.RelPath, msg.res.Err)
@@ -515,6 +819,8 @@ func (m *model) View() string {
 	switch m.state {
 	case stateConfig:
 		return m.viewConfig()
+	case statePlan:
+		return m.viewPlan()
 	case stateRunning:
 		return m.viewRunning()
 	case stateDone:
@@ -550,13 +856,17 @@ func (m *model) versionLabel() string {
 
 func (m *model) viewConfig() string {
 	rows := []string{
-		titleStyle.Render(fmt.Sprintf("%s ▸ Markdown → AAC (OpenAI)", m.versionLabel())),
-		fmt.Sprintf("%s %s", labelStyle.Render("API key:"), presentMissing(os.Getenv("OPENAI_API_KEY"))),
+		titleStyle.Render(fmt.Sprintf("%s ▸ Markdown → %s (%s)", m.versionLabel(), strings.ToUpper(m.format()), m.provider().Name)),
+		m.apiKeyRow(),
 		"",
 		fmt.Sprintf("%s\n%s", labelStyle.Render("Input directory"), m.inputs[0].View()),
 		fmt.Sprintf("%s\n%s", labelStyle.Render("Output directory"), m.inputs[1].View()),
 		fmt.Sprintf("%s\n%s", labelStyle.Render("Voice"), m.inputs[2].View()),
-		fmt.Sprintf("%s %s", labelStyle.Render("Overwrite existing [o]:"), boolBadge(m.overwrite)),
+		fmt.Sprintf("%s\n%s", labelStyle.Render("Provider (←/→)"), selectorView(m.provider().Name, m.providerFocused())),
+		fmt.Sprintf("%s\n%s", labelStyle.Render("Format (←/→)"), selectorView(m.format(), m.formatFocused())),
+		fmt.Sprintf("%s\n%s", labelStyle.Render("API base URL"), m.inputs[3].View()),
+		fmt.Sprintf("%s\n%s", labelStyle.Render("Deployment"), m.inputs[4].View()),
+		fmt.Sprintf("%s %s", labelStyle.Render("Existing audio [o]:"), m.existingBadge()),
 	}
 
 	if m.err != nil {
@@ -566,11 +876,32 @@ func (m *model) viewConfig() string {
 		rows = append(rows, dimStyle.Render(m.message))
 	}
 
-	rows = append(rows, dimStyle.Render(m.versionLabel()+" · tab/shift+tab to move · enter to start · o to toggle overwrite · q to quit"))
+	rows = append(rows, dimStyle.Render(m.versionLabel()+" · tab/shift+tab to move · enter to start · ctrl+o to cycle skip/update/overwrite · ctrl+c to quit"))
 
 	return boxStyle.Width(76).Render(strings.Join(rows, "\n"))
 }
 
+// selectorView renders a ←/→ selector, highlighted when focused.
+func selectorView(value string, focused bool) string {
+	label := fmt.Sprintf("◂ %s ▸", value)
+	if focused {
+		return focusedStyle.Render("> " + label)
+	}
+	return "  " + label
+}
+
+// apiKeyRow reports whether the selected provider's API key is set.
+func (m *model) apiKeyRow() string {
+	env := m.provider().KeyEnv
+	if env == "" {
+		return fmt.Sprintf("%s %s", labelStyle.Render("API key:"), dimStyle.Render("not needed"))
+	}
+	if m.provider().KeyOptional && strings.TrimSpace(os.Getenv(env)) == "" {
+		return fmt.Sprintf("%s %s", labelStyle.Render(fmt.Sprintf("API key (%s):", env)), dimStyle.Render("not set (optional)"))
+	}
+	return fmt.Sprintf("%s %s", labelStyle.Render(fmt.Sprintf("API key (%s):", env)), presentMissing(os.Getenv(env)))
+}
+
 func presentMissing(v string) string {
 	if strings.TrimSpace(v) == "" {
 		return errorStyle.Render("missing")
@@ -578,11 +909,16 @@ func presentMissing(v string) string {
 	return successStyle.Render("found")
 }
 
-func boolBadge(v bool) string {
-	if v {
-		return successStyle.Render("ON")
+// existingBadge describes how existing audio is handled.
+func (m *model) existingBadge() string {
+	switch {
+	case m.overwrite:
+		return successStyle.Render("overwrite all")
+	case m.update:
+		return successStyle.Render("update changed")
+	default:
+		return dimStyle.Render("skip")
 	}
-	return dimStyle.Render("off")
 }
 
 func (m *model) renderActive() []string {
@@ -610,6 +946,8 @@ func (m *model) renderActive() []string {
 			state = dimStyle.Render("skipped")
 		case "empty":
 			state = dimStyle.Render("empty")
+		case "not attempted":
+			state = dimStyle.Render("not attempted")
 		}
 		line := fmt.Sprintf("%s %s %s %s", valueStyle.Render("•"), progress, valueStyle.Render(ts.name), labelStyle.Render(state))
 		lines = append(lines, line)
@@ -664,9 +1002,17 @@ func (m *model) viewRunning() string {
 		titleStyle.Render(fmt.Sprintf("%s — Synthesizing…", m.versionLabel())),
 		bar,
 		summary,
+	}
+	if m.cfg.Cache != nil {
+		lines = append(lines, m.cacheLine())
+	}
+	if m.cfg.RateLimit != nil {
+		lines = append(lines, m.rateLine())
+	}
+	lines = append(lines,
 		"",
 		labelStyle.Render("Active files:"),
-	}
+	)
 
 	active := m.renderActive()
 	if len(active) == 0 {
@@ -675,7 +1021,9 @@ func (m *model) viewRunning() string {
 		lines = append(lines, active...)
 	}
 
-	if m.lastError != "" {
+	if m.stopReason != "" {
+		lines = append(lines, "", errorStyle.Render("Stopping: "+m.stopReason))
+	} else if m.lastError != "" {
 		lines = append(lines, "", errorStyle.Render("Last error (see log)"))
 	}
 	if m.logPath != "" {
@@ -686,6 +1034,39 @@ func (m *model) viewRunning() string {
 	return boxStyle.Width(76).Render(strings.Join(lines, "\n"))
 }
 
+// cacheLine renders the audio cache hit and miss counts.
+func (m *model) cacheLine() string {
+	return fmt.Sprintf("%s %d  %s %d",
+		labelStyle.Render("cache hits"), m.summary.CacheHits,
+		labelStyle.Render("misses"), m.summary.CacheMisses,
+	)
+}
+
+// rateLine renders the rate limiter's current pace, and whether 429s have
+// throttled it.
+func (m *model) rateLine() string {
+	stats := m.cfg.RateLimit.Stats()
+	var limits []string
+	if stats.RPM > 0 {
+		limits = append(limits, fmt.Sprintf("%.0f req/min", stats.RPM))
+	}
+	if stats.CharsPerMin > 0 {
+		limits = append(limits, fmt.Sprintf("%.0f chars/min", stats.CharsPerMin))
+	}
+	pace := "unlimited"
+	if len(limits) > 0 {
+		pace = strings.Join(limits, ", ")
+	}
+	line := fmt.Sprintf("%s %s", labelStyle.Render("rate"), pace)
+	if stats.Throttled {
+		line += "  " + emphStyle.Render(fmt.Sprintf("throttled after %d× 429", stats.Throttles))
+	}
+	if stats.Waiting > 0 {
+		line += "  " + dimStyle.Render(fmt.Sprintf("%d waiting", stats.Waiting))
+	}
+	return line
+}
+
 func (m *model) viewDone() string {
 	lines := []string{
 		titleStyle.Render(fmt.Sprintf("%s — All done!", m.versionLabel())),
@@ -696,9 +1077,30 @@ func (m *model) viewDone() string {
 			errorStyle.Render("failed"), m.summary.Failed,
 		),
 		fmt.Sprintf("%s %s", labelStyle.Render("Output"), valueStyle.Render(filepath.Clean(m.cfg.Out))),
-		"",
-		emphStyle.Render("Press enter to run again, q to quit."),
 	}
+	if m.cfg.Cache != nil {
+		lines = append(lines, m.cacheLine())
+	}
+	if m.summary.Resumed > 0 {
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("%d chunks resumed from checkpoints", m.summary.Resumed)))
+	}
+	if n := m.cfg.RateLimit.Stats().Throttles; n > 0 {
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("rate limited %d times (429); the pace was lowered to match", n)))
+	}
+	if m.stopReason != "" {
+		lines = append(lines, errorStyle.Render("Stopped: "+m.stopReason))
+	}
+	if len(m.notAttempted) > 0 {
+		sort.Strings(m.notAttempted)
+		shown := m.notAttempted
+		more := ""
+		if len(shown) > 5 {
+			more = fmt.Sprintf(" (+%d more)", len(shown)-5)
+			shown = shown[:5]
+		}
+		lines = append(lines, labelStyle.Render(fmt.Sprintf("%d not attempted: %s%s", len(m.notAttempted), strings.Join(shown, ", "), more)))
+	}
+	lines = append(lines, "", emphStyle.Render("Press enter to run again, q to quit."))
 	if m.summary.Failed > 0 && m.logPath != "" {
 		lines = append(lines, errorStyle.Render("Errors logged to: "+m.logPath))
 	}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/markloud/markloud"
+)
+
+func TestStartConversionUsesProviderInputs(t *testing.T) {
+	urls := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case urls <- r.URL.String():
+		default:
+		}
+		w.Write([]byte("AUDIO"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(filepath.Join(dir, "note.md"), []byte("Hello."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel(&CLIOptions{Provider: "openai-compatible", NoCache: true}, VersionInfo{})
+	m.inputs[0].SetValue(dir)
+	m.inputs[1].SetValue(filepath.Join(dir, "out"))
+	m.inputs[3].SetValue(srv.URL)
+	m.inputs[4].SetValue("speech")
+
+	_, cmd := m.startConversion()
+	if m.logFile != nil {
+		defer m.logFile.Close()
+	}
+	msg, ok := cmd().(preparedMsg)
+	if !ok {
+		t.Fatalf("prepare failed: %+v", msg)
+	}
+	msg.conv.ConvertFile(context.Background(), msg.jobs[0], markloud.Progress{})
+	select {
+	case got := <-urls:
+		if !strings.HasPrefix(got, "/openai/deployments/speech/audio/speech?api-version=") {
+			t.Fatalf("request went to %q, want the typed deployment", got)
+		}
+	default:
+		t.Fatal("no request reached the typed base URL")
+	}
+}
+
+func TestTypingCommandKeysIntoInputs(t *testing.T) {
+	m := initialModel(&CLIOptions{Provider: "azure-openai"}, VersionInfo{})
+	for inputAt(m.focusIndex) != 3 {
+		m.handleKey(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	const url = "https://qa-tts.openai.azure.com"
+	for _, r := range url {
+		m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if got := m.inputs[3].Value(); got != url {
+		t.Fatalf("base URL = %q, want %q", got, url)
+	}
+	if m.update || m.overwrite {
+		t.Fatal("typing \"o\" changed existing audio handling")
+	}
+
+	m.handleKey(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if !m.update {
+		t.Error("ctrl+o should cycle existing audio handling while typing")
+	}
+	m.focusIndex = focusProvider
+	m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+	if !m.overwrite {
+		t.Error("o should cycle existing audio handling on a selector")
+	}
+}
+
+func TestPrepareRunIgnoresCostLimitForLocalProviders(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "note.md"), []byte("Hello."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := markloud.Config{Root: dir, Out: t.TempDir(), Provider: "command", ResponseFormat: "wav"}
+	opts := &CLIOptions{Command: "cat", CommandFormat: "wav", MaxCost: 1, NoCache: true}
+	conv, _, err := prepareRun(cfg, opts)
+	if err != nil {
+		t.Fatalf("prepareRun with -max-cost on a local provider: %v", err)
+	}
+	if conv.Config().Budget != nil {
+		t.Error("a cost limit alone should not set up a budget for a local provider")
+	}
+
+	opts.MaxChars = 100
+	if conv, _, err = prepareRun(cfg, opts); err != nil {
+		t.Fatal(err)
+	}
+	if conv.Config().Budget == nil {
+		t.Error("a character limit should still set up a budget for a local provider")
+	}
+}
@@ -0,0 +1,345 @@
+// Package markloud converts Markdown files to speech.
+//
+// A Converter is built from a Config, collects the Markdown files under
+// Config.Root, estimates a run with Plan, and converts one file with
+// ConvertFile or all of them with ConvertAll:
+//
+//	conv, err := markloud.New(markloud.Config{Root: "notes", Out: "audio"})
+//	if err != nil {
+//		return err
+//	}
+//	jobs, err := conv.Collect()
+//	if err != nil {
+//		return err
+//	}
+//	summary := conv.ConvertAll(ctx, jobs, 4, markloud.Progress{
+//		File: func(job markloud.FileJob, res markloud.JobResult) {
+//			log.Printf("%s: %s", job.RelPath, res.Status)
+//		},
+//	})
+//
+// The markloud command and its TUI are built on this package.
+package markloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/markloud/markloud/internal/convert"
+)
+
+type (
+	// Config holds the settings of a run; see New for the defaults it fills.
+	Config = convert.Config
+	// FileJob is one Markdown file and the audio file it becomes.
+	FileJob = convert.FileJob
+	// JobResult is the outcome of converting one file.
+	JobResult = convert.JobResult
+	// JobOutcome says whether a file was converted, skipped or failed.
+	JobOutcome = convert.JobOutcome
+	// Chunk is the text of one TTS request and its section heading.
+	Chunk = convert.Chunk
+	// CacheStats counts chunks found in, or missing from, the audio cache.
+	CacheStats = convert.CacheStats
+	// Plan is the estimate for a run; see Converter.Plan.
+	Plan = convert.Plan
+	// FilePlan is the estimate for one file.
+	FilePlan = convert.FilePlan
+
+	// TTSClient synthesizes speech for one chunk of text.
+	TTSClient = convert.TTSClient
+	// ProviderInfo describes a registered TTS provider.
+	ProviderInfo = convert.ProviderInfo
+	// ProviderConfig carries the settings a provider needs to build its
+	// client.
+	ProviderConfig = convert.ProviderConfig
+	// RetryPolicy says how HTTP providers retry failed requests.
+	RetryPolicy = convert.RetryPolicy
+
+	// Budget caps the characters, cost and files of a run.
+	Budget = convert.Budget
+	// RateLimiter paces requests per minute and characters per minute
+	// across the workers of a run.
+	RateLimiter = convert.RateLimiter
+	// RateStats is a snapshot of a RateLimiter.
+	RateStats = convert.RateStats
+	// Cache stores synthesized chunks across runs.
+	Cache = convert.Cache
+
+	// TableMode selects how tables are spoken.
+	TableMode = convert.TableMode
+	// CodeBlockMode selects how code blocks are spoken.
+	CodeBlockMode = convert.CodeBlockMode
+	// URLMode selects how bare URLs are spoken.
+	URLMode = convert.URLMode
+
+	// ModelPrice is the list price of a TTS model.
+	ModelPrice = convert.ModelPrice
+
+	// Option configures a Converter.
+	Option = convert.Option
+)
+
+// Outcomes of converting one file.
+const (
+	JobDone         = convert.JobDone
+	JobSkipped      = convert.JobSkipped
+	JobEmpty        = convert.JobEmpty
+	JobFailed       = convert.JobFailed
+	JobNotAttempted = convert.JobNotAttempted
+)
+
+const (
+	// DefaultProvider is the TTS provider used when Config.Provider is empty.
+	DefaultProvider = convert.DefaultProvider
+	// DefaultResponseFormat is the audio format of the default provider.
+	DefaultResponseFormat = convert.DefaultResponseFormat
+	// DefaultPattern matches the files Collect picks up when
+	// Config.Pattern is empty.
+	DefaultPattern = "*.md"
+)
+
+// ErrBudgetExceeded is returned, wrapped, when a Budget stops a run.
+var ErrBudgetExceeded = convert.ErrBudgetExceeded
+
+// WithTTSClient makes the Converter synthesize with client instead of
+// building one for Config.Provider.
+func WithTTSClient(client TTSClient) Option { return convert.WithTTSClient(client) }
+
+// WithProviderConfig configures the client built for Config.Provider.
+func WithProviderConfig(pc ProviderConfig) Option { return convert.WithProviderConfig(pc) }
+
+// WithHTTPClient sets the HTTP client of the client built for
+// Config.Provider.
+func WithHTTPClient(client *http.Client) Option { return convert.WithHTTPClient(client) }
+
+// WithLogger makes the Converter log its progress to logger.
+func WithLogger(logger *slog.Logger) Option { return convert.WithLogger(logger) }
+
+// Providers lists the registered TTS providers, the default first.
+func Providers() []ProviderInfo { return convert.Providers() }
+
+// LookupProvider returns the provider registered as name; the empty name
+// selects DefaultProvider.
+func LookupProvider(name string) (ProviderInfo, bool) { return convert.LookupProvider(name) }
+
+// ProviderFormats returns the audio formats the provider registered as name
+// produces with pc.
+func ProviderFormats(name string, pc ProviderConfig) ([]string, error) {
+	client, err := convert.NewTTSClient(name, pc)
+	if err != nil {
+		return nil, err
+	}
+	return convert.FormatsOf(client), nil
+}
+
+// ModelPrices lists the known TTS model prices.
+func ModelPrices() []ModelPrice { return slices.Clone(convert.ModelPrices) }
+
+// PriceFor returns the price of model, matched by the longest known prefix.
+func PriceFor(model string) (ModelPrice, bool) { return convert.PriceFor(model) }
+
+// NewBudget returns a Budget for Config.Budget. A zero limit is not
+// enforced; a cost limit needs a model with a known price.
+func NewBudget(maxChars int, maxCost float64, maxFiles int, model string) (*Budget, error) {
+	return convert.NewBudget(maxChars, maxCost, maxFiles, model)
+}
+
+// NewRateLimiter returns a RateLimiter for Config.RateLimit allowing rpm
+// requests and charsPerMin characters a minute; zero is unlimited. Its rate
+// drops when the provider answers 429 and recovers after a quiet spell.
+func NewRateLimiter(rpm, charsPerMin int) *RateLimiter {
+	return convert.NewRateLimiter(rpm, charsPerMin)
+}
+
+// OpenCache opens the audio cache in dir for Config.Cache, creating it if
+// needed. maxBytes <= 0 uses the default size limit.
+func OpenCache(dir string, maxBytes int64) (*Cache, error) {
+	return convert.OpenCache(dir, maxBytes)
+}
+
+// DefaultCacheDir returns the audio cache directory in the user's cache
+// directory.
+func DefaultCacheDir() (string, error) { return convert.DefaultCacheDir() }
+
+// Converter converts the Markdown files of one Config. It is safe for
+// concurrent use.
+type Converter struct {
+	conv *convert.Converter
+}
+
+// New returns a Converter for cfg. Empty Provider, Model, Voice,
+// ResponseFormat and APIKey take the provider's defaults (the key from the
+// provider's environment variable), and an empty Pattern means
+// DefaultPattern. Root must be a directory; its .markloud.yaml and the
+// lexicon in cfg.LexiconPath are loaded unless cfg.Lexicon is already set.
+func New(cfg Config, opts ...Option) (*Converter, error) {
+	provider, ok := convert.LookupProvider(cfg.Provider)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+	}
+	cfg.Provider = provider.Name
+	if cfg.Model == "" {
+		cfg.Model = provider.DefaultModel
+	}
+	if cfg.Voice == "" {
+		cfg.Voice = provider.DefaultVoice
+	}
+	if cfg.ResponseFormat == "" {
+		cfg.ResponseFormat = provider.DefaultFormat
+	}
+	if cfg.APIKey == "" && provider.KeyEnv != "" {
+		cfg.APIKey = strings.TrimSpace(os.Getenv(provider.KeyEnv))
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = DefaultPattern
+	}
+
+	info, err := os.Stat(cfg.Root)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("input directory not found: %s", cfg.Root)
+	}
+	project, err := convert.LoadProjectConfig(cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	cfg = project.Apply(cfg)
+	if cfg.Lexicon == nil {
+		if cfg.Lexicon, err = convert.ResolveLexicon(cfg.Root, cfg.LexiconPath); err != nil {
+			return nil, err
+		}
+	}
+
+	conv, err := convert.NewConverter(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{conv: conv}, nil
+}
+
+// Config returns the Converter's configuration with defaults filled in.
+func (c *Converter) Config() Config {
+	return c.conv.Config()
+}
+
+// Collect returns a job for every file under Config.Root matching
+// Config.Pattern, with its audio file mirrored under Config.Out.
+func (c *Converter) Collect() ([]FileJob, error) {
+	cfg := c.conv.Config()
+	return convert.CollectMarkdownFiles(cfg.Root, cfg.Out, cfg.Pattern, cfg.ResponseFormat)
+}
+
+// Plan estimates the chunks, characters, cost and audio length of jobs
+// without synthesizing anything.
+func (c *Converter) Plan(jobs []FileJob) Plan {
+	return c.conv.PlanJobs(jobs)
+}
+
+// Progress receives updates while files convert. Either callback may be nil.
+// ConvertAll calls them from its worker goroutines, so they must be safe for
+// concurrent use.
+type Progress struct {
+	// Chunk is called before each chunk of job is synthesized, with current
+	// counting from 1, and once with current 0 when the file starts.
+	Chunk func(job FileJob, current, total int)
+	// File is called when job has finished, whatever its outcome.
+	File func(job FileJob, res JobResult)
+}
+
+// ConvertFile converts one file.
+func (c *Converter) ConvertFile(ctx context.Context, job FileJob, p Progress) JobResult {
+	var progress func(current, total int)
+	if p.Chunk != nil {
+		progress = func(current, total int) { p.Chunk(job, current, total) }
+	}
+	res := c.conv.ProcessFile(ctx, job, progress)
+	if p.File != nil {
+		p.File(job, res)
+	}
+	return res
+}
+
+// FileResult pairs a job with its outcome.
+type FileResult struct {
+	Job    FileJob
+	Result JobResult
+}
+
+// Summary is the outcome of ConvertAll.
+type Summary struct {
+	// Files holds one result per job, in the order of the jobs.
+	Files        []FileResult
+	Done         int
+	Skipped      int
+	Empty        int
+	Failed       int
+	NotAttempted int
+	// Resumed counts chunks taken from checkpoints.
+	Resumed int
+	Cache   CacheStats
+	// Stopped says why the run ended before attempting every file: an
+	// error wrapping ErrBudgetExceeded, or the context's error. It is nil
+	// otherwise.
+	Stopped error
+}
+
+// ConvertAll converts jobs with up to workers files in flight (at least
+// one). When the Budget runs out, files not yet started are left
+// JobNotAttempted.
+func (c *Converter) ConvertAll(ctx context.Context, jobs []FileJob, workers int, p Progress) Summary {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := Summary{Files: make([]FileResult, len(jobs))}
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res := c.ConvertFile(ctx, job, p)
+			if errors.Is(res.Err, ErrBudgetExceeded) {
+				cancel()
+			}
+			s.Files[i] = FileResult{Job: job, Result: res}
+		}()
+	}
+	wg.Wait()
+
+	for _, f := range s.Files {
+		switch f.Result.Status {
+		case JobDone:
+			s.Done++
+		case JobSkipped:
+			s.Skipped++
+		case JobEmpty:
+			s.Empty++
+		case JobFailed:
+			s.Failed++
+		case JobNotAttempted:
+			s.NotAttempted++
+		}
+		s.Resumed += f.Result.Resumed
+		s.Cache.Hits += f.Result.Cache.Hits
+		s.Cache.Misses += f.Result.Cache.Misses
+		if s.Stopped == nil && errors.Is(f.Result.Err, ErrBudgetExceeded) {
+			s.Stopped = f.Result.Err
+		}
+	}
+	if s.Stopped == nil && s.NotAttempted > 0 {
+		s.Stopped = parent.Err()
+	}
+	return s
+}
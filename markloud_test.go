@@ -0,0 +1,169 @@
+package markloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeClient returns the same audio for every chunk and counts its calls.
+type fakeClient struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *fakeClient) Synthesize(context.Context, Config, string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return []byte("A"), nil
+}
+
+func writeNotes(t *testing.T, names ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("# "+name+"\n\nSome text."), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestNewFillsDefaults(t *testing.T) {
+	root := writeNotes(t, "a.md")
+	conv, err := New(Config{Root: root, Out: t.TempDir()}, WithTTSClient(&fakeClient{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := conv.Config()
+	if cfg.Provider != DefaultProvider || cfg.Voice != "alloy" || cfg.ResponseFormat != DefaultResponseFormat || cfg.Pattern != DefaultPattern {
+		t.Fatalf("defaults not filled: %+v", cfg)
+	}
+
+	if _, err := New(Config{Root: filepath.Join(root, "missing")}); err == nil {
+		t.Error("missing input directory should fail")
+	}
+	if _, err := New(Config{Root: root, Provider: "nope"}); err == nil {
+		t.Error("unknown provider should fail")
+	}
+	var log bytes.Buffer
+	if _, err := New(Config{Root: root, Voice: "marin"}, WithLogger(slog.New(slog.NewTextHandler(&log, nil)))); err != nil {
+		t.Errorf("a voice missing from the provider's list should pass through: %v", err)
+	}
+	if !strings.Contains(log.String(), "voice=marin") {
+		t.Errorf("unlisted voice not logged: %q", log.String())
+	}
+}
+
+func TestOpenAICompatibleSendsOptionalKey(t *testing.T) {
+	auth := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case auth <- r.Header.Get("Authorization"):
+		default:
+		}
+		w.Write([]byte("AUDIO"))
+	}))
+	defer srv.Close()
+
+	t.Setenv("OPENAI_API_KEY", "sk-proxy")
+	root := writeNotes(t, "a.md")
+	conv, err := New(Config{Root: root, Out: t.TempDir(), Provider: "openai-compatible"},
+		WithProviderConfig(ProviderConfig{BaseURL: srv.URL}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := conv.Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := conv.ConvertFile(context.Background(), jobs[0], Progress{}); res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if got := <-auth; got != "Bearer sk-proxy" {
+		t.Fatalf("Authorization = %q, want the OPENAI_API_KEY bearer token", got)
+	}
+}
+
+func TestConvertAll(t *testing.T) {
+	root := writeNotes(t, "a.md", "b.md", "sub/c.md")
+	out := t.TempDir()
+	client := &fakeClient{}
+	conv, err := New(Config{Root: root, Out: out}, WithTTSClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := conv.Collect()
+	if err != nil || len(jobs) != 3 {
+		t.Fatalf("Collect = %d jobs, %v", len(jobs), err)
+	}
+	if plan := conv.Plan(jobs); plan.Convert != 3 || client.calls != 0 {
+		t.Fatalf("plan = %+v after %d calls", plan, client.calls)
+	}
+
+	var mu sync.Mutex
+	chunks, files := 0, map[string]JobOutcome{}
+	summary := conv.ConvertAll(context.Background(), jobs, 2, Progress{
+		Chunk: func(job FileJob, current, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			if current > 0 {
+				chunks++
+			}
+		},
+		File: func(job FileJob, res JobResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			files[job.RelPath] = res.Status
+		},
+	})
+	if summary.Done != 3 || summary.Stopped != nil || len(summary.Files) != 3 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	for i, f := range summary.Files {
+		if f.Job != jobs[i] || files[f.Job.RelPath] != JobDone {
+			t.Errorf("file %d = %+v, reported %v", i, f, files[f.Job.RelPath])
+		}
+	}
+	if chunks != client.calls {
+		t.Errorf("reported %d chunks for %d calls", chunks, client.calls)
+	}
+	if _, err := os.Stat(filepath.Join(out, "sub", "c.aac")); err != nil {
+		t.Error(err)
+	}
+
+	again := conv.ConvertAll(context.Background(), jobs, 2, Progress{})
+	if again.Skipped != 3 {
+		t.Fatalf("second run = %+v, want every file skipped", again)
+	}
+}
+
+func TestConvertAllStopsAtBudget(t *testing.T) {
+	root := writeNotes(t, "a.md", "b.md", "c.md")
+	budget, err := NewBudget(0, 0, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv, err := New(Config{Root: root, Out: t.TempDir(), Budget: budget}, WithTTSClient(&fakeClient{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ := conv.Collect()
+	summary := conv.ConvertAll(context.Background(), jobs, 1, Progress{})
+	if summary.Done != 1 || summary.NotAttempted != 2 || !errors.Is(summary.Stopped, ErrBudgetExceeded) {
+		t.Fatalf("summary = %+v", summary)
+	}
+}